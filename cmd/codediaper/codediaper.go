@@ -21,6 +21,7 @@ import (
 	"fmt"
 	"github.com/future-architect/code-diaper/condition"
 	"github.com/future-architect/code-diaper/diaper"
+	"github.com/future-architect/code-diaper/formatter"
 	"github.com/future-architect/code-diaper/reporter"
 	"github.com/kelseyhightower/envconfig"
 	"log"
@@ -34,6 +35,9 @@ func (s *SearchSentenceArgs) String() string {
 }
 
 func (s *SearchSentenceArgs) Set(value string) error {
+	if err := condition.Sentence(value).Validate(); err != nil {
+		return err
+	}
 	*s = append(*s, condition.Sentence(value))
 	return nil
 }
@@ -41,6 +45,37 @@ func (s *SearchSentenceArgs) Set(value string) error {
 func main() {
 	ctx := context.Background()
 
+	if len(os.Args) > 1 && os.Args[1] == "triage" {
+		if err := runTriage(ctx, os.Args[2:]); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "local" {
+		if err := runLocal(ctx, os.Args[2:]); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "fingerprint" {
+		if err := runFingerprint(os.Args[2:]); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "serve" {
+		if err := runServe(os.Args[2:]); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "config" {
+		if err := runConfig(os.Args[2:]); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
 	var envOps condition.Options
 	if err := envconfig.Process("", &envOps); err != nil {
 		panic(err)
@@ -53,56 +88,154 @@ func main() {
 	fs.Var(&searchSentenceList, "searchWord", "SearchList word that represents leak key word")
 
 	var (
-		githubToken   = fs.String("githubToken", "", "Github access token")
-		skipOwnerList = fs.String("skipOwners", "", "Skip repository owner name list. comma separated. if contained in file path then skipped")
-		skipRepoList  = fs.String("skipRepos", "", "Skip repository name list. comma separated. if matched exactly then skipped")
-		skipLibList   = fs.String("skipLibs", "", "Skip library name list. comma separated. if contained in file path then skipped")
-		slackEnabled  = fs.Bool("slackEnabled", false, "Slack notification enabled. default false")
-		slackToken    = fs.String("slackToken", "", "Slack access token")
-		slackChannel  = fs.String("slackChannel", "", "Slack channel ID")
+		configPath           = fs.String("config", "", "Config file path. YAML, TOML or JSON")
+		githubToken          = fs.String("githubToken", "", "Github access token")
+		githubBaseURL        = fs.String("githubBaseURL", "", "GitHub Enterprise Server API base URL. e.g. https://github.example.com/api/v3/")
+		githubUploadURL      = fs.String("githubUploadURL", "", "GitHub Enterprise Server upload URL. default is same as githubBaseURL")
+		githubIssueRepo      = fs.String("githubIssueRepo", "", "Tracking repository owner/name for -reporters=github_issue")
+		gitlabToken          = fs.String("gitlabToken", "", "GitLab access token")
+		gitlabBaseURL        = fs.String("gitlabBaseURL", "", "GitLab API base URL. default https://gitlab.com/api/v4/")
+		provider             = fs.String("provider", "", "Code hosting service to search. github or gitlab. default github")
+		skipOwnerList        = fs.String("skipOwners", "", "Skip repository owner name list. comma separated. if contained in file path then skipped")
+		skipRepoList         = fs.String("skipRepos", "", "Skip repository name list. comma separated. if matched exactly then skipped")
+		skipLibList          = fs.String("skipLibs", "", "Skip library name list. comma separated. if contained in file path then skipped")
+		reporterList         = fs.String("reporters", "", "Reporter name list to notify the result. comma separated. slack, teams, smtp, webhook or github_issue")
+		slackEnabled         = fs.Bool("slackEnabled", false, "Slack notification enabled. same as -reporters=slack. default false")
+		slackToken           = fs.String("slackToken", "", "Slack access token")
+		slackUploadHTML      = fs.Bool("slackUploadHTML", false, "Attach the HTML report to the Slack thread. default false")
+		slackChannel         = fs.String("slackChannel", "", "Slack channel ID")
+		teamsWebhookURL      = fs.String("teamsWebhookURL", "", "Microsoft Teams incoming webhook URL")
+		smtpAddr             = fs.String("smtpAddr", "", "SMTP server address. host:port")
+		smtpUsername         = fs.String("smtpUsername", "", "SMTP user name. AUTH PLAIN is used if set")
+		smtpPassword         = fs.String("smtpPassword", "", "SMTP password")
+		smtpFrom             = fs.String("smtpFrom", "", "From address of the email digest")
+		smtpTo               = fs.String("smtpTo", "", "Recipient list of the email digest. comma separated")
+		smtpStartTLS         = fs.Bool("smtpStartTLS", false, "Require STARTTLS. default false")
+		webhookURL           = fs.String("webhookURL", "", "URL to post the result for -reporters=webhook")
+		webhookSecret        = fs.String("webhookSecret", "", "Secret of the HMAC-SHA256 signature header of the webhook")
+		webhookTemplate      = fs.String("webhookTemplate", "", "text/template of the webhook payload. default is the JSON of the result")
+		concurrency          = fs.Int("concurrency", 0, "Number of searches executed at the same time. default 4")
+		verify               = fs.Bool("verify", false, "Download the hit files and match their whole content instead of the fragments. default false")
+		verifyMaxSize        = fs.Int("verifyMaxSize", 0, "Byte size limit of the files downloaded by -verify. default 393216")
+		fingerprintIndex     = fs.String("fingerprintIndex", "", "Fingerprint index file path. if set then the hit files are compared with the indexed private sources")
+		fingerprintThreshold = fs.Float64("fingerprintThreshold", 0, "Similarity score to report an indexed file. 0 to 1. default 0.5")
+		attribution          = fs.Bool("attribution", false, "Look up the last commit and the commit that added the hit line of each file. default false")
+		storePath            = fs.String("store", "", "Finding store file path. if set then only new and resolved findings are reported")
+		format               = fs.String("format", formatter.FormatText, "Output format. text, json, ndjson, sarif or html")
+		outputPath           = fs.String("output", "", "Output file path. default stdout")
+		reportAll            = fs.Bool("reportAll", false, "Report still-present findings too. default false")
 	)
 
 	if err := fs.Parse(os.Args[1:]); err != nil {
 		log.Fatal(err)
 	}
+	switch *format {
+	case formatter.FormatText, formatter.FormatJSON, formatter.FormatNDJSON, formatter.FormatSARIF, formatter.FormatHTML:
+	default:
+		log.Fatalf("unknown format: %v", *format)
+	}
 
 	cliOps := condition.Options{
-		GitHubToken: *githubToken,
-		SearchList: []condition.Search{
+		GitHubToken:          *githubToken,
+		GitHubBaseURL:        *githubBaseURL,
+		GitHubUploadURL:      *githubUploadURL,
+		GitHubIssueRepo:      *githubIssueRepo,
+		GitLabToken:          *gitlabToken,
+		GitLabBaseURL:        *gitlabBaseURL,
+		Reporters:            condition.ParseList(*reporterList),
+		SlackToken:           *slackToken,
+		SlackChannel:         *slackChannel,
+		SlackUploadHTML:      *slackUploadHTML,
+		TeamsWebhookURL:      *teamsWebhookURL,
+		SMTPAddr:             *smtpAddr,
+		SMTPUsername:         *smtpUsername,
+		SMTPPassword:         *smtpPassword,
+		SMTPFrom:             *smtpFrom,
+		SMTPTo:               condition.ParseList(*smtpTo),
+		SMTPStartTLS:         *smtpStartTLS,
+		WebhookURL:           *webhookURL,
+		WebhookSecret:        *webhookSecret,
+		WebhookTemplate:      *webhookTemplate,
+		Concurrency:          *concurrency,
+		Verify:               *verify,
+		VerifyMaxSize:        *verifyMaxSize,
+		FingerprintIndex:     *fingerprintIndex,
+		FingerprintThreshold: *fingerprintThreshold,
+		Attribution:          *attribution,
+		StorePath:            *storePath,
+		ReportAll:            *reportAll,
+	}
+
+	if *slackEnabled && !contains(cliOps.Reporters, reporter.NameSlack) {
+		cliOps.Reporters = append(cliOps.Reporters, reporter.NameSlack)
+	}
+
+	if len(searchSentenceList) > 0 {
+		cliOps.SearchList = []condition.Search{
 			{
+				Provider:   *provider,
 				QueryList:  searchSentenceList,
-				SkipOwners: *skipOwnerList,
-				SkipRepos:  *skipRepoList,
-				SkipLibs:   *skipLibList,
+				SkipOwners: condition.ParseList(*skipOwnerList),
+				SkipRepos:  condition.ParseList(*skipRepoList),
+				SkipLibs:   condition.ParseList(*skipLibList),
 			},
-		},
-		SlackToken:   *slackToken,
-		SlackChannel: *slackChannel,
+		}
 	}
 
-	ops := envOps.Override(cliOps)
-	message, err := diaper.Run(ctx, ops)
+	// env < config file < command line arguments
+	fileOps := condition.Options{}
+	if *configPath != "" {
+		var err error
+		fileOps, err = condition.LoadFile(*configPath)
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	ops := envOps.Override(fileOps)
+	ops = ops.Override(cliOps)
+
+	reporters, err := reporter.New(ops)
 	if err != nil {
 		log.Fatal(err)
 	}
 
-	fmt.Println(message.Summary)
-	for _, v := range message.Details {
-		fmt.Println(v)
+	result, err := diaper.Run(ctx, ops)
+	if err != nil {
+		log.Fatal(err)
 	}
 
-	if *slackEnabled {
-		slack := reporter.NewSlackReporter(ops.SlackToken, ops.SlackChannel)
+	if err := writeResult(*outputPath, *format, result); err != nil {
+		log.Fatal(err)
+	}
 
-		ts, err := slack.Post(ctx, message.Summary)
-		if err != nil {
-			log.Fatal(err)
-		}
+	if err := reporter.ReportAll(ctx, reporters, result); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// writeResult writes the result to the file, or stdout if path is empty.
+func writeResult(path, format string, result *formatter.ScanResult) error {
+	if path == "" {
+		return formatter.Write(os.Stdout, format, *result)
+	}
+
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := formatter.Write(f, format, *result); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
 
-		for _, v := range message.Details {
-			if err := slack.PostThread(ctx, ts, v); err != nil {
-				log.Fatal(err)
-			}
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
 		}
 	}
+	return false
 }
@@ -0,0 +1,81 @@
+/**
+ * Copyright (c) 2019-present Future Corporation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"github.com/future-architect/code-diaper/condition"
+	"github.com/future-architect/code-diaper/crawler"
+	"github.com/future-architect/code-diaper/diaper"
+	"os"
+	"strings"
+)
+
+// runConfig handles the subcommands for the config file.
+// usage: codediaper config validate -config codediaper.yaml
+func runConfig(args []string) error {
+	if len(args) == 0 || args[0] != "validate" {
+		return errors.New("usage: codediaper config validate -config <path>")
+	}
+
+	fs := flag.NewFlagSet("codediaper config validate", flag.ContinueOnError)
+	fs.SetOutput(os.Stderr)
+	configPath := fs.String("config", "", "Config file path. YAML, TOML or JSON")
+	if err := fs.Parse(args[1:]); err != nil {
+		return err
+	}
+	if *configPath == "" && fs.NArg() > 0 {
+		*configPath = fs.Arg(0)
+	}
+	if *configPath == "" {
+		return errors.New("required parameter: config")
+	}
+
+	ops, problems, err := condition.ValidateFile(*configPath)
+	if err != nil {
+		return err
+	}
+	if len(problems) > 0 {
+		for _, p := range problems {
+			fmt.Fprintln(os.Stderr, p)
+		}
+		return fmt.Errorf("%v: %d problem(s) found", *configPath, len(problems))
+	}
+
+	calls := map[string]int{}
+	var hosts []string
+	for i, v := range ops.SearchList {
+		for _, s := range v.Expand() {
+			host := diaper.Host(ops, s)
+			fmt.Printf("search_list[%d] %v %v\n", i, s.ProviderName(), strings.Join(s.QueryWordList(), "+"))
+			if _, ok := calls[host]; !ok {
+				hosts = append(hosts, host)
+			}
+			calls[host]++
+		}
+	}
+
+	// a search needs 1 request per page, and more if the hits exceed the limit and the query is split by size
+	maxPages := crawler.MaxSearchResults / crawler.MaxPageSize
+	for _, host := range hosts {
+		fmt.Printf("%v: %d searches, estimated %d-%d search API calls (+1 call per hit repository for the fork source)\n",
+			host, calls[host], calls[host], calls[host]*maxPages)
+	}
+	fmt.Printf("%v: OK\n", *configPath)
+	return nil
+}
@@ -0,0 +1,119 @@
+/**
+ * Copyright (c) 2019-present Future Corporation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"github.com/future-architect/code-diaper/condition"
+	"github.com/future-architect/code-diaper/fingerprint"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+// runFingerprint handles the subcommands for the fingerprint index of the private source trees.
+// usage: codediaper fingerprint index -out fingerprints.json <dir>...
+//
+//	codediaper fingerprint match -index fingerprints.json <file or dir>...
+func runFingerprint(args []string) error {
+	if len(args) == 0 {
+		return errors.New("usage: codediaper fingerprint index|match")
+	}
+	switch args[0] {
+	case "index":
+		return runFingerprintIndex(args[1:])
+	case "match":
+		return runFingerprintMatch(args[1:])
+	default:
+		return fmt.Errorf("unknown subcommand: %v", args[0])
+	}
+}
+
+func runFingerprintIndex(args []string) error {
+	fs := flag.NewFlagSet("codediaper fingerprint index", flag.ContinueOnError)
+	fs.SetOutput(os.Stderr)
+	out := fs.String("out", "fingerprints.json", "Output file path of the index")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() == 0 {
+		return errors.New("required parameter: directories to index")
+	}
+
+	idx, err := fingerprint.Build(fs.Args())
+	if err != nil {
+		return err
+	}
+	if err := idx.Save(*out); err != nil {
+		return err
+	}
+	fmt.Printf("%d files are indexed: %v\n", len(idx.Files), *out)
+	return nil
+}
+
+// runFingerprintMatch checks local files, e.g. a clone of a suspicious repository, against the index.
+func runFingerprintMatch(args []string) error {
+	fs := flag.NewFlagSet("codediaper fingerprint match", flag.ContinueOnError)
+	fs.SetOutput(os.Stderr)
+	var (
+		indexPath = fs.String("index", os.Getenv("FINGERPRINT_INDEX"), "Fingerprint index file path")
+		threshold = fs.Float64("threshold", condition.DefaultFingerprintThreshold, "Similarity score to report. 0 to 1")
+	)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *indexPath == "" {
+		return errors.New("required parameter: index")
+	}
+	if fs.NArg() == 0 {
+		return errors.New("required parameter: files or directories to check")
+	}
+
+	idx, err := fingerprint.Load(*indexPath)
+	if err != nil {
+		return err
+	}
+	for _, root := range fs.Args() {
+		err := filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+			if info.IsDir() {
+				if info.Name() == ".git" {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			if !info.Mode().IsRegular() {
+				return nil
+			}
+			b, err := ioutil.ReadFile(path)
+			if err != nil {
+				return err
+			}
+			for _, m := range idx.Match(string(b), *threshold) {
+				fmt.Printf("%v: %v (%.0f%%, lines %v)\n", path, m.Path, m.Score*100, m.Lines)
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,100 @@
+/**
+ * Copyright (c) 2019-present Future Corporation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"github.com/future-architect/code-diaper/condition"
+	"github.com/future-architect/code-diaper/crawler"
+	"github.com/future-architect/code-diaper/diaper"
+	"github.com/future-architect/code-diaper/formatter"
+	"os"
+	"strings"
+)
+
+// runLocal searches a working tree or the git history of a local repository with the same queries and skip lists.
+// It fails if something is found, so that it can be used in a git hook.
+// usage: codediaper local -config codediaper.yaml -dir . -history -rev origin/master..HEAD
+func runLocal(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("codediaper local", flag.ContinueOnError)
+	fs.SetOutput(os.Stderr)
+
+	var searchSentenceList = SearchSentenceArgs{}
+	fs.Var(&searchSentenceList, "searchWord", "SearchList word that represents leak key word")
+
+	var (
+		configPath       = fs.String("config", "", "Config file path. YAML, TOML or JSON")
+		dir              = fs.String("dir", ".", "Directory to search")
+		history          = fs.Bool("history", false, "Search the lines added in the git history instead of the working tree")
+		rev              = fs.String("rev", "", "Revision range of the history. space separated arguments of git log. default all refs")
+		skipLibList      = fs.String("skipLibs", "", "Skip library name list. comma separated. if contained in file path then skipped")
+		format           = fs.String("format", formatter.FormatText, "Output format. text, json, ndjson, sarif or html")
+		outputPath       = fs.String("output", "", "Output file path. default stdout")
+		fingerprintIndex = fs.String("fingerprintIndex", "", "Fingerprint index file path. if set then the hit files are compared with the indexed private sources")
+		attribution      = fs.Bool("attribution", false, "Look up the last commit and the commit that added the hit line of each file")
+	)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	ops := condition.Options{}
+	if *configPath != "" {
+		var err error
+		ops, err = condition.LoadFile(*configPath)
+		if err != nil {
+			return err
+		}
+	}
+	if *fingerprintIndex != "" {
+		ops.FingerprintIndex = *fingerprintIndex
+	}
+	if *attribution {
+		ops.Attribution = true
+	}
+	if len(searchSentenceList) > 0 {
+		ops.SearchList = []condition.Search{
+			{
+				QueryList: searchSentenceList,
+				SkipLibs:  condition.ParseList(*skipLibList),
+			},
+		}
+	}
+
+	gc, err := crawler.NewLocalCrawler(*dir, *history, strings.Fields(*rev))
+	if err != nil {
+		return err
+	}
+	result, err := diaper.RunLocal(ctx, ops, gc)
+	if err != nil {
+		return err
+	}
+	if err := writeResult(*outputPath, *format, result); err != nil {
+		return err
+	}
+
+	var count int
+	for _, sr := range result.Searches {
+		for _, repo := range sr.Repos {
+			count += len(repo.HitFiles)
+		}
+	}
+	if count > 0 {
+		return fmt.Errorf("%d file(s) contain the search words", count)
+	}
+	return nil
+}
@@ -0,0 +1,58 @@
+/**
+ * Copyright (c) 2019-present Future Corporation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"github.com/future-architect/code-diaper/reporter"
+	"github.com/future-architect/code-diaper/store"
+	"log"
+	"net/http"
+	"os"
+)
+
+// runServe receives the triage buttons of Slack messages.
+// usage: codediaper serve -addr :8080 -store findings.json -slackSigningSecret <secret>
+func runServe(args []string) error {
+	fs := flag.NewFlagSet("codediaper serve", flag.ContinueOnError)
+	fs.SetOutput(os.Stderr)
+
+	var (
+		addr          = fs.String("addr", ":8080", "Listen address")
+		storePath     = fs.String("store", os.Getenv("STORE_PATH"), "Finding store file path, or gs://bucket/object of Cloud Storage")
+		signingSecret = fs.String("slackSigningSecret", os.Getenv("SLACK_SIGNING_SECRET"), "Slack signing secret to verify requests")
+	)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if *storePath == "" {
+		return errors.New("required parameter: store")
+	}
+	if *signingSecret == "" {
+		return errors.New("required parameter: slackSigningSecret")
+	}
+
+	st, err := store.NewStore(context.Background(), *storePath)
+	if err != nil {
+		return err
+	}
+	http.Handle("/slack/interactions", reporter.NewSlackTriageHandler(*signingSecret, st))
+	log.Printf("listening on %v", *addr)
+	return http.ListenAndServe(*addr, nil)
+}
@@ -0,0 +1,75 @@
+/**
+ * Copyright (c) 2019-present Future Corporation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"github.com/future-architect/code-diaper/store"
+	"os"
+	"time"
+)
+
+// runTriage marks a finding as false-positive, acknowledged or remediated.
+// usage: codediaper triage -store findings.json -id <ID> -state false-positive -note "sample code of our OSS"
+func runTriage(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("codediaper triage", flag.ContinueOnError)
+	fs.SetOutput(os.Stderr)
+
+	var (
+		storePath = fs.String("store", os.Getenv("STORE_PATH"), "Finding store file path, or gs://bucket/object of Cloud Storage")
+		id        = fs.String("id", "", "Finding ID printed in the result")
+		state     = fs.String("state", "", "Triage state. false-positive, acknowledged or remediated")
+		note      = fs.String("note", "", "Note for the decision")
+	)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if *storePath == "" {
+		return errors.New("required parameter: store")
+	}
+	if *id == "" {
+		return errors.New("required parameter: id")
+	}
+	triageState, err := store.ParseTriageState(*state)
+	if err != nil {
+		return err
+	}
+
+	st, err := store.NewStore(ctx, *storePath)
+	if err != nil {
+		return err
+	}
+	findings, err := st.Load(ctx)
+	if err != nil {
+		return err
+	}
+
+	findings, err = findings.SetTriage(*id, triageState, *note, time.Now())
+	if err != nil {
+		return err
+	}
+	if err := st.Save(ctx, findings); err != nil {
+		return err
+	}
+
+	f := findings[findings.Index(*id)]
+	fmt.Printf("%v is marked as %v: %v\n", f.ID, f.Triage.State, f.FileURL)
+	return nil
+}
@@ -16,23 +16,71 @@
 package condition
 
 import (
+	"encoding/json"
 	"github.com/kujtimiihoxha/go-brace-expansion"
 	"strings"
 	"unsafe"
 )
 
+const (
+	ProviderGitHub = "github"
+	ProviderGitLab = "gitlab"
+)
+
+// DefaultConcurrency is the number of searches executed at the same time.
+const DefaultConcurrency = 4
+
+// DefaultVerifyMaxSize is the byte size limit of a file downloaded to verify the hits. Same as the limit of GitHub code search.
+const DefaultVerifyMaxSize = 384 * 1024
+
+// DefaultFingerprintThreshold is the similarity score to report an internal file as copied.
+const DefaultFingerprintThreshold = 0.5
+
 type Options struct {
-	GitHubToken  string   `json:"github_token"  envconfig:"GITHUB_API_TOKEN"`
-	SlackToken   string   `json:"slack_token"   envconfig:"SLACK_API_TOKEN"`
-	SlackChannel string   `json:"slack_channel" envconfig:"SLACK_CHANNEL"`
-	SearchList   []Search `json:"search_list"`
+	GitHubToken          string   `json:"github_token"      envconfig:"GITHUB_API_TOKEN"`
+	GitHubBaseURL        string   `json:"github_base_url"   envconfig:"GITHUB_BASE_URL"`
+	GitHubUploadURL      string   `json:"github_upload_url" envconfig:"GITHUB_UPLOAD_URL"`
+	GitHubIssueRepo      string   `json:"github_issue_repo" envconfig:"GITHUB_ISSUE_REPO"` // tracking repository "owner/name" of the github_issue reporter
+	GitLabToken          string   `json:"gitlab_token"      envconfig:"GITLAB_API_TOKEN"`
+	GitLabBaseURL        string   `json:"gitlab_base_url"   envconfig:"GITLAB_BASE_URL"`
+	Reporters            List     `json:"reporters"         envconfig:"REPORTERS"` // e.g. ["slack"]
+	SlackToken           string   `json:"slack_token"       envconfig:"SLACK_API_TOKEN"`
+	SlackChannel         string   `json:"slack_channel"     envconfig:"SLACK_CHANNEL"`
+	SlackSigningSecret   string   `json:"slack_signing_secret" envconfig:"SLACK_SIGNING_SECRET"` // verifies the triage buttons
+	SlackUploadHTML      bool     `json:"slack_upload_html" envconfig:"SLACK_UPLOAD_HTML"`       // attach the HTML report to the thread
+	TeamsWebhookURL      string   `json:"teams_webhook_url" envconfig:"TEAMS_WEBHOOK_URL"`
+	SMTPAddr             string   `json:"smtp_addr"         envconfig:"SMTP_ADDR"` // host:port
+	SMTPUsername         string   `json:"smtp_username"     envconfig:"SMTP_USERNAME"`
+	SMTPPassword         string   `json:"smtp_password"     envconfig:"SMTP_PASSWORD"`
+	SMTPFrom             string   `json:"smtp_from"         envconfig:"SMTP_FROM"`
+	SMTPTo               List     `json:"smtp_to"           envconfig:"SMTP_TO"` // receive all searches
+	SMTPStartTLS         bool     `json:"smtp_starttls"     envconfig:"SMTP_STARTTLS"`
+	WebhookURL           string   `json:"webhook_url"       envconfig:"WEBHOOK_URL"`
+	WebhookSecret        string   `json:"webhook_secret"    envconfig:"WEBHOOK_SECRET"`   // key of the HMAC-SHA256 signature
+	WebhookTemplate      string   `json:"webhook_template"  envconfig:"WEBHOOK_TEMPLATE"` // text/template of the payload. default is the JSON of the result
+	Concurrency          int      `json:"concurrency"       envconfig:"CONCURRENCY"`
+	Verify               bool     `json:"verify"            envconfig:"VERIFY"`                    // download the hit files and match their whole content
+	VerifyMaxSize        int      `json:"verify_max_size"   envconfig:"VERIFY_MAX_SIZE"`           // bytes. larger files are judged by the fragments
+	FingerprintIndex     string   `json:"fingerprint_index"     envconfig:"FINGERPRINT_INDEX"`     // built by "codediaper fingerprint index"
+	FingerprintThreshold float64  `json:"fingerprint_threshold" envconfig:"FINGERPRINT_THRESHOLD"` // 0 to 1
+	Attribution          bool     `json:"attribution"       envconfig:"ATTRIBUTION"`               // look up the commits of the hit files
+	StorePath            string   `json:"store_path"        envconfig:"STORE_PATH"`                // findings are deduplicated across runs if set
+	ReportAll            bool     `json:"report_all"        envconfig:"REPORT_ALL"`                // report still-present findings too
+	SearchList           []Search `json:"search_list"`
 }
 
 type Search struct {
+	Provider   string     `json:"provider"` // "github"(default) or "gitlab"
 	QueryList  []Sentence `json:"queries"`
-	SkipRepos  string     `json:"skip_repos"`
-	SkipLibs   string     `json:"skip_libs"`
-	SkipOwners string     `json:"skip_owners"`
+	SkipRepos  List       `json:"skip_repos"`
+	SkipLibs   List       `json:"skip_libs"`
+	SkipOwners List       `json:"skip_owners"`
+	MailTo     List       `json:"mail_to"` // recipients of the smtp reporter for this search
+
+	// GitHub Enterprise Server settings. If empty, the values of Options are used.
+	GitHubToken     string `json:"github_token"`
+	GitHubBaseURL   string `json:"github_base_url"`
+	GitHubUploadURL string `json:"github_upload_url"`
 }
 
 func (o Options) ExpandSearch() []Search {
@@ -47,8 +95,42 @@ func (s Search) StringWordList() []string {
 	return *(*[]string)(unsafe.Pointer(&s.QueryList))
 }
 
+// QueryWordList returns the words for the search API. Regular expressions are replaced by the literal words derived from them,
+// and boolean expressions are compiled into GitHub search strings.
+func (s Search) QueryWordList() []string {
+	var result []string
+	for _, v := range s.QueryList {
+		if v.IsExpression() {
+			if e, err := v.Compile(); err == nil {
+				result = append(result, e.query())
+			}
+			continue
+		}
+		if v.IsPattern() {
+			result = append(result, strings.Join(v.Parse(), "+"))
+			continue
+		}
+		result = append(result, string(v))
+	}
+	return result
+}
+
 func (s Search) Expand() []Search {
-	expand := gobrex.Expand(strings.Join(s.StringWordList(), "\n"))
+	var words []string
+	var patterns []Sentence
+	for _, v := range s.QueryList {
+		if v.IsPattern() || (v.IsExpression() && strings.Contains(string(v), "/")) {
+			// braces of regular expression such as "{2}" are not expanded
+			patterns = append(patterns, v)
+			continue
+		}
+		words = append(words, string(v))
+	}
+
+	if len(words) == 0 {
+		return []Search{s}
+	}
+	expand := gobrex.Expand(strings.Join(words, "\n"))
 	if len(expand) == 1 {
 		// Not expand result
 		return []Search{s}
@@ -56,12 +138,9 @@ func (s Search) Expand() []Search {
 
 	var result []Search
 	for _, v := range expand {
-		result = append(result, Search{
-			QueryList:  Sentences(strings.Split(v, "\n")),
-			SkipOwners: s.SkipOwners,
-			SkipRepos:  s.SkipRepos,
-			SkipLibs:   s.SkipLibs,
-		})
+		e := s
+		e.QueryList = append(Sentences(strings.Split(v, "\n")), patterns...)
+		result = append(result, e)
 	}
 
 	return result
@@ -69,7 +148,27 @@ func (s Search) Expand() []Search {
 
 type Sentence string
 
+// UnmarshalJSON validates the sentence, so that a malformed query is reported at config load.
+func (s *Sentence) UnmarshalJSON(b []byte) error {
+	var v string
+	if err := json.Unmarshal(b, &v); err != nil {
+		return err
+	}
+	if err := Sentence(v).Validate(); err != nil {
+		return err
+	}
+	*s = Sentence(v)
+	return nil
+}
+
+// Parse returns the words that must be contained in the same line,
+// or in the same order within 3 consecutive lines, so that a header wrapped into lines is found.
+// If the sentence is a regular expression, the literal words derived from it are returned.
 func (s Sentence) Parse() []string {
+	if s.IsPattern() {
+		return s.literalWords()
+	}
+
 	var result []string
 	split := strings.Split(string(s), "+")
 	for _, v := range split {
@@ -83,6 +182,80 @@ func (s Sentence) Parse() []string {
 	return result
 }
 
+// Keywords returns the words to highlight in the fragments.
+// Regular expressions are highlighted by Patterns instead of their literal words.
+func (s Sentence) Keywords() []string {
+	if s.IsPattern() {
+		return nil
+	}
+	if s.IsExpression() {
+		e, err := s.Compile()
+		if err != nil {
+			return nil
+		}
+		return keywords(e)
+	}
+
+	var result []string
+	for _, v := range s.Parse() {
+		if v != "" {
+			result = append(result, v)
+		}
+	}
+	return result
+}
+
+// Keywords returns the words to highlight in the fragments of the search.
+func (s Search) Keywords() []string {
+	var result []string
+	for _, v := range s.QueryList {
+		result = append(result, v.Keywords()...)
+	}
+	return result
+}
+
+// ProviderName returns the code hosting service of the search. GitHub is the default.
+func (s Search) ProviderName() string {
+	if s.Provider == "" {
+		return ProviderGitHub
+	}
+	return s.Provider
+}
+
+func (o Options) ConcurrencyOrDefault() int {
+	if o.Concurrency <= 0 {
+		return DefaultConcurrency
+	}
+	return o.Concurrency
+}
+
+func (o Options) VerifyMaxSizeOrDefault() int {
+	if o.VerifyMaxSize <= 0 {
+		return DefaultVerifyMaxSize
+	}
+	return o.VerifyMaxSize
+}
+
+func (o Options) FingerprintThresholdOrDefault() float64 {
+	if o.FingerprintThreshold <= 0 {
+		return DefaultFingerprintThreshold
+	}
+	return o.FingerprintThreshold
+}
+
+// GitHubEndpoint returns the token and API URLs for the search. Values of the search take precedence over the options.
+// baseURL is empty when the search is for github.com.
+func (o Options) GitHubEndpoint(s Search) (token, baseURL, uploadURL string) {
+	token, baseURL, uploadURL = o.GitHubToken, o.GitHubBaseURL, o.GitHubUploadURL
+	if s.GitHubToken != "" {
+		token = s.GitHubToken
+	}
+	if s.GitHubBaseURL != "" {
+		baseURL, uploadURL = s.GitHubBaseURL, s.GitHubUploadURL
+	}
+	return token, baseURL, uploadURL
+}
+
 func Sentences(arr []string) []Sentence {
 	var res []Sentence
 	for _, v := range arr {
@@ -93,22 +266,126 @@ func Sentences(arr []string) []Sentence {
 
 func (o *Options) Override(overOptions Options) Options {
 	result := Options{
-		GitHubToken:  o.GitHubToken,
-		SlackToken:   o.SlackToken,
-		SlackChannel: o.SlackChannel,
+		GitHubToken:          o.GitHubToken,
+		GitHubBaseURL:        o.GitHubBaseURL,
+		GitHubUploadURL:      o.GitHubUploadURL,
+		GitHubIssueRepo:      o.GitHubIssueRepo,
+		GitLabToken:          o.GitLabToken,
+		GitLabBaseURL:        o.GitLabBaseURL,
+		Reporters:            o.Reporters,
+		SlackToken:           o.SlackToken,
+		SlackChannel:         o.SlackChannel,
+		SlackUploadHTML:      o.SlackUploadHTML,
+		SlackSigningSecret:   o.SlackSigningSecret,
+		TeamsWebhookURL:      o.TeamsWebhookURL,
+		SMTPAddr:             o.SMTPAddr,
+		SMTPUsername:         o.SMTPUsername,
+		SMTPPassword:         o.SMTPPassword,
+		SMTPFrom:             o.SMTPFrom,
+		SMTPTo:               o.SMTPTo,
+		SMTPStartTLS:         o.SMTPStartTLS,
+		WebhookURL:           o.WebhookURL,
+		WebhookSecret:        o.WebhookSecret,
+		WebhookTemplate:      o.WebhookTemplate,
+		Concurrency:          o.Concurrency,
+		Verify:               o.Verify,
+		VerifyMaxSize:        o.VerifyMaxSize,
+		FingerprintIndex:     o.FingerprintIndex,
+		FingerprintThreshold: o.FingerprintThreshold,
+		Attribution:          o.Attribution,
+		StorePath:            o.StorePath,
+		ReportAll:            o.ReportAll,
 	}
 
 	if overOptions.GitHubToken != "" {
 		result.GitHubToken = overOptions.GitHubToken
 	}
+	if overOptions.GitHubBaseURL != "" {
+		result.GitHubBaseURL = overOptions.GitHubBaseURL
+	}
+	if overOptions.GitHubUploadURL != "" {
+		result.GitHubUploadURL = overOptions.GitHubUploadURL
+	}
+	if overOptions.GitHubIssueRepo != "" {
+		result.GitHubIssueRepo = overOptions.GitHubIssueRepo
+	}
+	if overOptions.GitLabToken != "" {
+		result.GitLabToken = overOptions.GitLabToken
+	}
+	if overOptions.GitLabBaseURL != "" {
+		result.GitLabBaseURL = overOptions.GitLabBaseURL
+	}
 	if len(overOptions.SearchList) != 0 {
 		result.SearchList = overOptions.SearchList
 	}
+	if len(overOptions.Reporters) != 0 {
+		result.Reporters = overOptions.Reporters
+	}
 	if overOptions.SlackToken != "" {
 		result.SlackToken = overOptions.SlackToken
 	}
 	if overOptions.SlackChannel != "" {
 		result.SlackChannel = overOptions.SlackChannel
 	}
+	if overOptions.SlackSigningSecret != "" {
+		result.SlackSigningSecret = overOptions.SlackSigningSecret
+	}
+	if overOptions.SlackUploadHTML {
+		result.SlackUploadHTML = overOptions.SlackUploadHTML
+	}
+	if overOptions.TeamsWebhookURL != "" {
+		result.TeamsWebhookURL = overOptions.TeamsWebhookURL
+	}
+	if overOptions.SMTPAddr != "" {
+		result.SMTPAddr = overOptions.SMTPAddr
+	}
+	if overOptions.SMTPUsername != "" {
+		result.SMTPUsername = overOptions.SMTPUsername
+	}
+	if overOptions.SMTPPassword != "" {
+		result.SMTPPassword = overOptions.SMTPPassword
+	}
+	if overOptions.SMTPFrom != "" {
+		result.SMTPFrom = overOptions.SMTPFrom
+	}
+	if len(overOptions.SMTPTo) != 0 {
+		result.SMTPTo = overOptions.SMTPTo
+	}
+	if overOptions.SMTPStartTLS {
+		result.SMTPStartTLS = overOptions.SMTPStartTLS
+	}
+	if overOptions.WebhookURL != "" {
+		result.WebhookURL = overOptions.WebhookURL
+	}
+	if overOptions.WebhookSecret != "" {
+		result.WebhookSecret = overOptions.WebhookSecret
+	}
+	if overOptions.WebhookTemplate != "" {
+		result.WebhookTemplate = overOptions.WebhookTemplate
+	}
+	if overOptions.Concurrency != 0 {
+		result.Concurrency = overOptions.Concurrency
+	}
+	if overOptions.Verify {
+		result.Verify = overOptions.Verify
+	}
+	if overOptions.VerifyMaxSize != 0 {
+		result.VerifyMaxSize = overOptions.VerifyMaxSize
+	}
+	if overOptions.FingerprintIndex != "" {
+		result.FingerprintIndex = overOptions.FingerprintIndex
+	}
+	if overOptions.FingerprintThreshold != 0 {
+		result.FingerprintThreshold = overOptions.FingerprintThreshold
+	}
+	if overOptions.Attribution {
+		result.Attribution = overOptions.Attribution
+	}
+	if overOptions.StorePath != "" {
+		result.StorePath = overOptions.StorePath
+	}
+	if overOptions.ReportAll {
+		result.ReportAll = overOptions.ReportAll
+	}
 	return result
 }
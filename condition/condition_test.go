@@ -0,0 +1,71 @@
+/**
+ * Copyright (c) 2019-present Future Corporation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package condition
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestPatternParse(t *testing.T) {
+	tests := []struct {
+		input    Sentence
+		expected []string
+	}{
+		{`/Copyright \(c\) 20[0-9]{2} Future/`, []string{"Copyright", "Future"}},
+		{`/\bFuture Corporation\b/`, []string{"Future", "Corporation"}},
+		{`/(?i)copyright 2019/`, []string{"copyright", "2019"}},
+		{`/Copy[a-z]+ Future Corp.*/`, []string{"Future"}},
+		{`/[0-9]+/`, nil},
+	}
+	for _, tt := range tests {
+		actual := tt.input.Parse()
+		if !reflect.DeepEqual(actual, tt.expected) {
+			t.Errorf("%v got: %v\nwant: %v", tt.input, actual, tt.expected)
+		}
+	}
+}
+
+func TestPatternValidate(t *testing.T) {
+	if err := Sentence(`/Copyright 20[0-9]{2} Future/`).Validate(); err != nil {
+		t.Error(err)
+	}
+	if err := Sentence(`/Copyright (2019/`).Validate(); err == nil {
+		t.Error("expected error for invalid regular expression")
+	}
+	if err := Sentence(`/[0-9]+/`).Validate(); err == nil {
+		t.Error("expected error for no literal word")
+	}
+	if err := Sentence(`/(c) [0-9]+/`).Validate(); err == nil {
+		t.Error("expected error for no literal word longer than 2")
+	}
+}
+
+func TestExpandWithPattern(t *testing.T) {
+	s := Search{QueryList: []Sentence{"Copyright+{2018,2019}", `/Future Corp[a-z]{0,9}/`}}
+
+	actual := s.Expand()
+	if len(actual) != 2 {
+		t.Fatalf("got: %v\nwant: %v", len(actual), 2)
+	}
+	expected := []string{"Copyright+2018", "Future"}
+	if !reflect.DeepEqual(actual[0].QueryWordList(), expected) {
+		t.Errorf("got: %v\nwant: %v", actual[0].QueryWordList(), expected)
+	}
+	if actual[1].QueryList[1] != `/Future Corp[a-z]{0,9}/` {
+		t.Errorf("got: %v", actual[1].QueryList[1])
+	}
+}
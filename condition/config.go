@@ -0,0 +1,135 @@
+/**
+ * Copyright (c) 2019-present Future Corporation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package condition
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"github.com/BurntSushi/toml"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sigs.k8s.io/yaml"
+	"strings"
+)
+
+// envVariable is "${NAME}". "$NAME" is not supported because "$" is often used in regular expressions.
+var envVariable = regexp.MustCompile(`\$\{([A-Za-z_][A-Za-z0-9_]*)\}`)
+
+// LoadFile reads the config file. The format is decided by the extension: .yaml, .yml, .toml or .json.
+// "${NAME}" in the string values is replaced by the environment variable.
+func LoadFile(path string) (Options, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return Options{}, err
+	}
+
+	jsonBytes, err := ToJSON(path, b)
+	if err == nil {
+		jsonBytes, err = ExpandEnv(jsonBytes)
+	}
+	if err != nil {
+		return Options{}, fmt.Errorf("%v: %v", path, err)
+	}
+
+	var ops Options
+	if err := json.Unmarshal(jsonBytes, &ops); err != nil {
+		return Options{}, fmt.Errorf("%v: %v", path, err)
+	}
+	return ops, nil
+}
+
+// ExpandEnv replaces "${NAME}" in the string values of the decoded JSON by the environment variable.
+// It is applied after decoding, so that a value of the variable can not change the structure of the file.
+func ExpandEnv(jsonBytes []byte) ([]byte, error) {
+	d := json.NewDecoder(bytes.NewReader(jsonBytes))
+	d.UseNumber()
+	var v interface{}
+	if err := d.Decode(&v); err != nil {
+		return nil, err
+	}
+	return json.Marshal(expandEnv(v))
+}
+
+func expandEnv(v interface{}) interface{} {
+	switch x := v.(type) {
+	case string:
+		return envVariable.ReplaceAllStringFunc(x, func(s string) string {
+			return os.Getenv(envVariable.FindStringSubmatch(s)[1])
+		})
+	case map[string]interface{}:
+		for key, value := range x {
+			x[key] = expandEnv(value)
+		}
+		return x
+	case []interface{}:
+		for i, value := range x {
+			x[i] = expandEnv(value)
+		}
+		return x
+	default:
+		return v
+	}
+}
+
+// ToJSON converts the content of YAML or TOML to JSON, so that all formats are decoded by the json tags of Options.
+func ToJSON(path string, b []byte) ([]byte, error) {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		return yaml.YAMLToJSON(b)
+	case ".toml":
+		var v map[string]interface{}
+		if _, err := toml.Decode(string(b), &v); err != nil {
+			return nil, err
+		}
+		return json.Marshal(v)
+	case ".json":
+		return b, nil
+	default:
+		return nil, fmt.Errorf("unsupported config format: %v", filepath.Ext(path))
+	}
+}
+
+// List is a string list. In JSON, both an array and a comma separated string are accepted.
+type List []string
+
+// ParseList splits the comma separated string.
+func ParseList(s string) List {
+	var result List
+	for _, v := range strings.Split(s, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			result = append(result, v)
+		}
+	}
+	return result
+}
+
+func (l *List) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err == nil {
+		*l = ParseList(s)
+		return nil
+	}
+
+	var arr []string
+	if err := json.Unmarshal(b, &arr); err != nil {
+		return fmt.Errorf("must be an array of strings or a comma separated string: %s", b)
+	}
+	*l = List(arr)
+	return nil
+}
@@ -0,0 +1,129 @@
+/**
+ * Copyright (c) 2019-present Future Corporation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package condition
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestLoadFile(t *testing.T) {
+	os.Setenv("CODEDIAPER_TEST_TOKEN", "dummy-token")
+	defer os.Unsetenv("CODEDIAPER_TEST_TOKEN")
+
+	tests := []struct {
+		name    string
+		content string
+	}{
+		{"config.yaml", `
+github_token: ${CODEDIAPER_TEST_TOKEN}
+search_list:
+  - queries: ["Copyright+2019+Future", '/Future Corp(oration)?/']
+    skip_owners: [future-architect, ghost]
+    skip_repos: repo1,repo2
+`},
+		{"config.toml", `
+github_token = "${CODEDIAPER_TEST_TOKEN}"
+[[search_list]]
+queries = ["Copyright+2019+Future", '/Future Corp(oration)?/']
+skip_owners = ["future-architect", "ghost"]
+skip_repos = "repo1,repo2"
+`},
+		{"config.json", `{
+  "github_token": "${CODEDIAPER_TEST_TOKEN}",
+  "search_list": [{
+    "queries": ["Copyright+2019+Future", "/Future Corp(oration)?/"],
+    "skip_owners": ["future-architect", "ghost"],
+    "skip_repos": "repo1,repo2"
+  }]
+}`},
+	}
+
+	dir, err := ioutil.TempDir("", "codediaper")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	want := Options{
+		GitHubToken: "dummy-token",
+		SearchList: []Search{{
+			QueryList:  []Sentence{"Copyright+2019+Future", `/Future Corp(oration)?/`},
+			SkipOwners: List{"future-architect", "ghost"},
+			SkipRepos:  List{"repo1", "repo2"},
+		}},
+	}
+	for _, tt := range tests {
+		path := filepath.Join(dir, tt.name)
+		if err := ioutil.WriteFile(path, []byte(tt.content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		actual, err := LoadFile(path)
+		if err != nil {
+			t.Errorf("%v: %v", tt.name, err)
+			continue
+		}
+		if !reflect.DeepEqual(actual, want) {
+			t.Errorf("%v got: %+v\nwant: %+v", tt.name, actual, want)
+		}
+	}
+}
+
+func TestLoadFileUnsupported(t *testing.T) {
+	f, err := ioutil.TempFile("", "config*.ini")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(f.Name())
+	f.Close()
+
+	if _, err := LoadFile(f.Name()); err == nil {
+		t.Errorf("got: nil\nwant: error")
+	}
+}
+
+func TestLoadFileExpandEnvValueOnly(t *testing.T) {
+	injected := "x\nsearch_list:\n  - queries: [injected]\n# \"}"
+	os.Setenv("CODEDIAPER_TEST_CHANNEL", injected)
+	defer os.Unsetenv("CODEDIAPER_TEST_CHANNEL")
+
+	dir, err := ioutil.TempDir("", "codediaper")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	for name, content := range map[string]string{
+		"config.yaml": "slack_channel: ${CODEDIAPER_TEST_CHANNEL}\nverify_max_size: 1048576\n",
+		"config.json": `{"slack_channel": "${CODEDIAPER_TEST_CHANNEL}"}`,
+	} {
+		path := filepath.Join(dir, name)
+		if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		actual, err := LoadFile(path)
+		if err != nil {
+			t.Errorf("%v: %v", name, err)
+			continue
+		}
+		if actual.SlackChannel != injected || len(actual.SearchList) != 0 || (name == "config.yaml" && actual.VerifyMaxSize != 1048576) {
+			t.Errorf("%v got: %+v", name, actual)
+		}
+	}
+}
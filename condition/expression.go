@@ -0,0 +1,472 @@
+/**
+ * Copyright (c) 2019-present Future Corporation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package condition
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+// Boolean expression of a sentence.
+//
+//   expr  := or
+//   or    := and ("OR" and)*
+//   and   := unary (["AND"] unary)*
+//   unary := "NOT" unary | "(" expr ")" | term
+//   term  := word | "quoted phrase" | /regular expression/ | qualifier:value
+//
+// e.g. `COPYRIGHT AND Future NOT "Apache License"`
+//
+// Unlike plain sentences, an expression is evaluated against the whole fragment, not each line,
+// so that NOT can exclude a license written on another line.
+// Words and phrases are matched case-insensitively like the search API, and regular expressions as they are written.
+
+// Expr is a compiled boolean expression.
+type Expr interface {
+	// Match reports whether the text satisfies the expression.
+	Match(text string) bool
+
+	// query returns the GitHub search string. It may match more than Match (never less),
+	// because GitHub can not express everything such as regular expressions.
+	query() string
+}
+
+// SyntaxError is a parse error of an expression. Pos is the byte offset in the sentence.
+type SyntaxError struct {
+	Sentence string
+	Pos      int
+	Msg      string
+}
+
+func (e *SyntaxError) Error() string {
+	return fmt.Sprintf("invalid query %q: %v at column %d", e.Sentence, e.Msg, e.Pos+1)
+}
+
+// IsExpression reports whether the sentence uses AND / OR / NOT operators.
+func (s Sentence) IsExpression() bool {
+	if s.IsPattern() {
+		return false
+	}
+	for _, v := range strings.FieldsFunc(string(s), isSeparator) {
+		if v == "AND" || v == "OR" || v == "NOT" {
+			return true
+		}
+	}
+	return false
+}
+
+// Compile parses the boolean expression of the sentence.
+func (s Sentence) Compile() (Expr, error) {
+	tokens, err := tokenize(string(s))
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{sentence: string(s), tokens: tokens}
+
+	e, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if t := p.peek(); t.kind != tokenEOF {
+		return nil, p.errorf(t, "unexpected %q", t.text)
+	}
+	if e.query() == "" || !hasPositive(e) {
+		return nil, &SyntaxError{Sentence: string(s), Msg: "at least one word without NOT is required to search"}
+	}
+	return e, nil
+}
+
+// hasPositive reports whether every match of the expression contains some word.
+func hasPositive(e Expr) bool {
+	switch x := e.(type) {
+	case wordExpr:
+		return true
+	case patternExpr:
+		return len(x.words) > 0
+	case andExpr:
+		for _, v := range x {
+			if hasPositive(v) {
+				return true
+			}
+		}
+		return false
+	case orExpr:
+		for _, v := range x {
+			if !hasPositive(v) {
+				return false
+			}
+		}
+		return true
+	default:
+		return false
+	}
+}
+
+// keywords returns the words that appear in the matched text. Words under NOT and qualifiers are excluded.
+func keywords(e Expr) []string {
+	switch x := e.(type) {
+	case wordExpr:
+		return []string{string(x)}
+	case andExpr:
+		var result []string
+		for _, v := range x {
+			result = append(result, keywords(v)...)
+		}
+		return result
+	case orExpr:
+		var result []string
+		for _, v := range x {
+			result = append(result, keywords(v)...)
+		}
+		return result
+	default:
+		return nil
+	}
+}
+
+// patterns returns the regular expressions of the expression except the negated ones.
+func patterns(e Expr) []string {
+	switch x := e.(type) {
+	case patternExpr:
+		return []string{x.re.String()}
+	case andExpr:
+		var result []string
+		for _, v := range x {
+			result = append(result, patterns(v)...)
+		}
+		return result
+	case orExpr:
+		var result []string
+		for _, v := range x {
+			result = append(result, patterns(v)...)
+		}
+		return result
+	default:
+		return nil
+	}
+}
+
+type tokenKind int
+
+const (
+	tokenEOF tokenKind = iota
+	tokenWord
+	tokenPhrase
+	tokenPattern
+	tokenAnd
+	tokenOr
+	tokenNot
+	tokenLParen
+	tokenRParen
+)
+
+type token struct {
+	kind tokenKind
+	text string
+	pos  int
+}
+
+func isSeparator(r rune) bool {
+	return unicode.IsSpace(r) || r == '+'
+}
+
+func tokenize(s string) ([]token, error) {
+	var tokens []token
+	for i := 0; i < len(s); {
+		c := s[i]
+		switch {
+		case isSeparator(rune(c)):
+			i++
+		case c == '(':
+			tokens = append(tokens, token{kind: tokenLParen, text: "(", pos: i})
+			i++
+		case c == ')':
+			tokens = append(tokens, token{kind: tokenRParen, text: ")", pos: i})
+			i++
+		case c == '"':
+			end := strings.IndexByte(s[i+1:], '"')
+			if end == -1 {
+				return nil, &SyntaxError{Sentence: s, Pos: i, Msg: "unterminated quotation"}
+			}
+			tokens = append(tokens, token{kind: tokenPhrase, text: s[i+1 : i+1+end], pos: i})
+			i += end + 2
+		case c == '/':
+			end := closingSlash(s, i+1)
+			if end == -1 {
+				return nil, &SyntaxError{Sentence: s, Pos: i, Msg: "unterminated regular expression"}
+			}
+			tokens = append(tokens, token{kind: tokenPattern, text: s[i : end+1], pos: i})
+			i = end + 1
+		default:
+			start := i
+			for i < len(s) && !isSeparator(rune(s[i])) && s[i] != '(' && s[i] != ')' {
+				i++
+			}
+			t := token{kind: tokenWord, text: s[start:i], pos: start}
+			switch t.text {
+			case "AND":
+				t.kind = tokenAnd
+			case "OR":
+				t.kind = tokenOr
+			case "NOT":
+				t.kind = tokenNot
+			}
+			tokens = append(tokens, t)
+		}
+	}
+	return append(tokens, token{kind: tokenEOF, pos: len(s)}), nil
+}
+
+// closingSlash returns the index of the slash that closes the regular expression.
+func closingSlash(s string, from int) int {
+	for i := from; i < len(s); i++ {
+		if s[i] == '\\' {
+			i++
+			continue
+		}
+		if s[i] == '/' {
+			return i
+		}
+	}
+	return -1
+}
+
+type parser struct {
+	sentence string
+	tokens   []token
+	pos      int
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *parser) next() token {
+	t := p.tokens[p.pos]
+	if t.kind != tokenEOF {
+		p.pos++
+	}
+	return t
+}
+
+func (p *parser) errorf(t token, format string, args ...interface{}) error {
+	return &SyntaxError{Sentence: p.sentence, Pos: t.pos, Msg: fmt.Sprintf(format, args...)}
+}
+
+func (p *parser) parseOr() (Expr, error) {
+	first := p.peek()
+	e, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	xs := []Expr{e}
+	for p.peek().kind == tokenOr {
+		p.next()
+		e, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		xs = append(xs, e)
+	}
+	if len(xs) == 1 {
+		return xs[0], nil
+	}
+	for _, x := range xs {
+		if _, ok := x.(qualifierExpr); ok {
+			return nil, p.errorf(first, "qualifier can not be used with OR")
+		}
+	}
+	return orExpr(xs), nil
+}
+
+func (p *parser) parseAnd() (Expr, error) {
+	e, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	xs := []Expr{e}
+	for {
+		switch p.peek().kind {
+		case tokenAnd:
+			p.next()
+		case tokenNot, tokenLParen, tokenWord, tokenPhrase, tokenPattern:
+			// implicit AND
+		default:
+			if len(xs) == 1 {
+				return xs[0], nil
+			}
+			return andExpr(xs), nil
+		}
+		e, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		xs = append(xs, e)
+	}
+}
+
+func (p *parser) parseUnary() (Expr, error) {
+	t := p.next()
+	switch t.kind {
+	case tokenNot:
+		e, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		if _, ok := e.(qualifierExpr); ok {
+			return nil, p.errorf(t, "NOT can not be applied to qualifier")
+		}
+		return notExpr{e}, nil
+	case tokenLParen:
+		e, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if r := p.next(); r.kind != tokenRParen {
+			return nil, p.errorf(r, "expected \")\"")
+		}
+		return e, nil
+	case tokenWord:
+		if strings.Contains(t.text, ":") {
+			// GitHub search syntax("extension:rb" OR "path:app" OR ...)
+			return qualifierExpr(t.text), nil
+		}
+		return wordExpr(t.text), nil
+	case tokenPhrase:
+		if t.text == "" {
+			return nil, p.errorf(t, "empty quotation")
+		}
+		return wordExpr(t.text), nil
+	case tokenPattern:
+		s := Sentence(t.text)
+		re, err := s.Regexp()
+		if err != nil {
+			return nil, p.errorf(t, "invalid regular expression %v: %v", s, err)
+		}
+		// no literal word is allowed in an expression, if the other words can be searched
+		return patternExpr{re: re, words: s.Parse()}, nil
+	case tokenEOF:
+		return nil, p.errorf(t, "unexpected end of query")
+	default:
+		return nil, p.errorf(t, "unexpected %q", t.text)
+	}
+}
+
+// wordExpr is a word or a quoted phrase. It is matched case-insensitively.
+type wordExpr string
+
+func (e wordExpr) Match(text string) bool {
+	return strings.Contains(strings.ToLower(text), strings.ToLower(string(e)))
+}
+
+func (e wordExpr) query() string {
+	if strings.ContainsAny(string(e), " \t") {
+		return `"` + string(e) + `"`
+	}
+	return string(e)
+}
+
+// qualifierExpr is only for GitHub search. It always matches because the fragment does not have the information.
+type qualifierExpr string
+
+func (e qualifierExpr) Match(text string) bool {
+	return true
+}
+
+func (e qualifierExpr) query() string {
+	return string(e)
+}
+
+type patternExpr struct {
+	re    *regexp.Regexp
+	words []string
+}
+
+func (e patternExpr) Match(text string) bool {
+	return e.re.MatchString(text)
+}
+
+func (e patternExpr) query() string {
+	return strings.Join(e.words, " ")
+}
+
+type andExpr []Expr
+
+func (e andExpr) Match(text string) bool {
+	for _, x := range e {
+		if !x.Match(text) {
+			return false
+		}
+	}
+	return true
+}
+
+func (e andExpr) query() string {
+	var qs []string
+	for _, x := range e {
+		q := x.query()
+		if q == "" {
+			continue
+		}
+		if _, ok := x.(orExpr); ok {
+			// OR binds more loosely than AND
+			q = "(" + q + ")"
+		}
+		qs = append(qs, q)
+	}
+	return strings.Join(qs, " ")
+}
+
+type orExpr []Expr
+
+func (e orExpr) Match(text string) bool {
+	for _, x := range e {
+		if x.Match(text) {
+			return true
+		}
+	}
+	return false
+}
+
+// query uses OR of GitHub only for plain words, otherwise nothing is narrowed down.
+func (e orExpr) query() string {
+	var qs []string
+	for _, x := range e {
+		if _, ok := x.(wordExpr); !ok {
+			return ""
+		}
+		qs = append(qs, x.query())
+	}
+	return strings.Join(qs, " OR ")
+}
+
+type notExpr struct {
+	x Expr
+}
+
+func (e notExpr) Match(text string) bool {
+	return !e.x.Match(text)
+}
+
+// query uses NOT of GitHub only for a plain word, otherwise nothing is narrowed down.
+func (e notExpr) query() string {
+	if _, ok := e.x.(wordExpr); !ok {
+		return ""
+	}
+	return "NOT " + e.x.query()
+}
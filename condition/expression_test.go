@@ -0,0 +1,150 @@
+/**
+ * Copyright (c) 2019-present Future Corporation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package condition
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestCompile(t *testing.T) {
+	tests := []struct {
+		input Sentence
+		query string
+		match []string
+		miss  []string
+	}{
+		{
+			input: `COPYRIGHT AND Future NOT "Apache License"`,
+			query: `COPYRIGHT Future NOT "Apache License"`,
+			match: []string{"COPYRIGHT 2019 Future Corporation"},
+			miss:  []string{"COPYRIGHT 2019 Future Corporation\n Licensed under the Apache License", "COPYRIGHT 2019 Example"},
+		},
+		{
+			input: `Copyright+(Future OR Example)+NOT+Apache`,
+			query: `Copyright (Future OR Example) NOT Apache`,
+			match: []string{"Copyright Example", "Copyright Future"},
+			miss:  []string{"Copyright Other", "Copyright Future Apache", "Example"},
+		},
+		{
+			input: `(Future OR "Example Inc") AND Copyright`,
+			query: `(Future OR "Example Inc") Copyright`,
+			match: []string{"Copyright Future", "Copyright Example Inc"},
+			miss:  []string{"Future", "Copyright Example"},
+		},
+		{
+			// words are case-insensitive like the search API, regular expressions are not
+			input: `copyright AND "future corporation" AND /Copyright/`,
+			query: `copyright "future corporation" Copyright`,
+			match: []string{"Copyright 2019 FUTURE Corporation"},
+			miss:  []string{"COPYRIGHT 2019 Future Corporation"},
+		},
+		{
+			input: `Future AND (/20[0-9]{2}/ OR Example) extension:java`,
+			query: `Future extension:java`,
+			match: []string{"Copyright 2019 Future", "Future Example"},
+			miss:  []string{"Copyright Future"},
+		},
+	}
+	for _, tt := range tests {
+		if !tt.input.IsExpression() {
+			t.Errorf("%v is not an expression", tt.input)
+		}
+		e, err := tt.input.Compile()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if e.query() != tt.query {
+			t.Errorf("%v got: %v\nwant: %v", tt.input, e.query(), tt.query)
+		}
+		for _, v := range tt.match {
+			if !e.Match(v) {
+				t.Errorf("%v must match %q", tt.input, v)
+			}
+		}
+		for _, v := range tt.miss {
+			if e.Match(v) {
+				t.Errorf("%v must not match %q", tt.input, v)
+			}
+		}
+	}
+}
+
+func TestCompileError(t *testing.T) {
+	tests := []struct {
+		input    Sentence
+		expected string
+	}{
+		{`Copyright AND (Future OR Example`, `expected ")" at column 33`},
+		{`Copyright AND "Future`, `unterminated quotation at column 15`},
+		{`Copyright AND`, `unexpected end of query at column 14`},
+		{`NOT Apache`, `at least one word without NOT is required`},
+		{`Copyright OR extension:java`, `qualifier can not be used with OR`},
+		{`Copyright AND /20[0-9/`, `missing closing ]`},
+	}
+	for _, tt := range tests {
+		_, err := tt.input.Compile()
+		if err == nil || !strings.Contains(err.Error(), tt.expected) {
+			t.Errorf("%v got: %v\nwant: %v", tt.input, err, tt.expected)
+		}
+	}
+}
+
+func TestUnmarshalInvalidSentence(t *testing.T) {
+	var s Search
+	err := json.Unmarshal([]byte(`{"queries": ["Copyright AND (Future"]}`), &s)
+	if err == nil || !strings.Contains(err.Error(), `invalid query "Copyright AND (Future"`) {
+		t.Errorf("got: %v", err)
+	}
+}
+
+func TestKeywords(t *testing.T) {
+	tests := []struct {
+		input    Sentence
+		expected []string
+	}{
+		{"Copyright+2019 Future", []string{"Copyright", "2019", "Future"}},
+		{"Copyright+extension:go", []string{"Copyright"}},
+		{`/Copyright \(c\) 20[0-9]{2} Future/`, nil},
+		{`COPYRIGHT AND (Future OR Example) NOT "Apache License"`, []string{"COPYRIGHT", "Future", "Example"}},
+		{`Future AND /20[0-9]{2}/`, []string{"Future"}},
+	}
+	for _, tt := range tests {
+		actual := tt.input.Keywords()
+		if !reflect.DeepEqual(actual, tt.expected) {
+			t.Errorf("%v got: %v\nwant: %v", tt.input, actual, tt.expected)
+		}
+	}
+}
+
+func TestPatterns(t *testing.T) {
+	tests := []struct {
+		input    Sentence
+		expected []string
+	}{
+		{"Copyright+2019 Future", nil},
+		{`/Copyright \(c\) 20[0-9]{2} Future/`, []string{`Copyright \(c\) 20[0-9]{2} Future`}},
+		{`Future AND (/20[0-9]{2}/ OR Example) NOT /Apache.*/`, []string{`20[0-9]{2}`}},
+	}
+	for _, tt := range tests {
+		actual := tt.input.Patterns()
+		if !reflect.DeepEqual(actual, tt.expected) {
+			t.Errorf("%v got: %v\nwant: %v", tt.input, actual, tt.expected)
+		}
+	}
+}
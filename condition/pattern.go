@@ -0,0 +1,160 @@
+/**
+ * Copyright (c) 2019-present Future Corporation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package condition
+
+import (
+	"fmt"
+	"regexp"
+	"regexp/syntax"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// IsPattern reports whether the sentence is a regular expression surrounded by slashes.
+// e.g. "/Copyright \(c\) 20[0-9]{2} Future/"
+func (s Sentence) IsPattern() bool {
+	return len(s) >= 2 && strings.HasPrefix(string(s), "/") && strings.HasSuffix(string(s), "/")
+}
+
+// Regexp compiles the regular expression of the sentence.
+func (s Sentence) Regexp() (*regexp.Regexp, error) {
+	if !s.IsPattern() {
+		return nil, fmt.Errorf("not a regular expression: %v", s)
+	}
+	return regexp.Compile(string(s[1 : len(s)-1]))
+}
+
+// Validate checks that the boolean expression can be parsed,
+// and that the regular expression can be compiled and has at least one literal word for the search API.
+func (s Sentence) Validate() error {
+	if s.IsExpression() {
+		_, err := s.Compile()
+		return err
+	}
+	if !s.IsPattern() {
+		return nil
+	}
+	if _, err := s.Regexp(); err != nil {
+		return fmt.Errorf("invalid regular expression %v: %v", s, err)
+	}
+	if len(s.literalWords()) == 0 {
+		return fmt.Errorf("regular expression %v has no literal word to search", s)
+	}
+	return nil
+}
+
+// minLiteralLength is the shortest literal word used for the search API.
+// A shorter word such as "c" of "(c)" hits too many files to narrow the search.
+const minLiteralLength = 3
+
+// literalWords returns the words that every match of the regular expression contains.
+// A word adjoining a non-literal part is dropped, because the search API matches whole words only.
+// A word shorter than minLiteralLength is dropped too.
+// e.g. "/Copyright \(c\) 20[0-9]{2} Future/" returns ["Copyright", "Future"]
+func (s Sentence) literalWords() []string {
+	re, err := syntax.Parse(string(s[1:len(s)-1]), syntax.Perl)
+	if err != nil {
+		return nil
+	}
+	re = re.Simplify()
+	for re.Op == syntax.OpCapture {
+		re = re.Sub[0]
+	}
+
+	subs := []*syntax.Regexp{re}
+	if re.Op == syntax.OpConcat {
+		subs = re.Sub
+	}
+
+	var result []string
+	for i, sub := range subs {
+		if sub.Op != syntax.OpLiteral {
+			continue
+		}
+		text := string(sub.Rune)
+		if sub.Flags&syntax.FoldCase != 0 {
+			// case-insensitive literal is held in upper case
+			text = strings.ToLower(text)
+		}
+		words := strings.Fields(text)
+		if len(words) == 0 {
+			continue
+		}
+
+		if i > 0 && !isBoundary(subs[i-1]) && !startsWithSpace(text) {
+			words = words[1:]
+		}
+		if i < len(subs)-1 && !isBoundary(subs[i+1]) && !endsWithSpace(text) && len(words) > 0 {
+			words = words[:len(words)-1]
+		}
+
+		for _, w := range words {
+			w = strings.TrimFunc(w, func(r rune) bool {
+				return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+			})
+			if utf8.RuneCountInString(w) >= minLiteralLength {
+				result = append(result, w)
+			}
+		}
+	}
+	return result
+}
+
+// Patterns returns the regular expressions to highlight in the fragments.
+// The sentence itself if it is a regular expression, or the regular expressions in the boolean expression.
+func (s Sentence) Patterns() []string {
+	if s.IsPattern() {
+		if _, err := s.Regexp(); err != nil {
+			return nil
+		}
+		return []string{string(s[1 : len(s)-1])}
+	}
+	if s.IsExpression() {
+		e, err := s.Compile()
+		if err != nil {
+			return nil
+		}
+		return patterns(e)
+	}
+	return nil
+}
+
+// Patterns returns the regular expressions to highlight in the fragments of the search.
+func (s Search) Patterns() []string {
+	var result []string
+	for _, v := range s.QueryList {
+		result = append(result, v.Patterns()...)
+	}
+	return result
+}
+
+// isBoundary reports whether the part of the regular expression never joins the adjoining word.
+func isBoundary(re *syntax.Regexp) bool {
+	switch re.Op {
+	case syntax.OpWordBoundary, syntax.OpBeginLine, syntax.OpEndLine, syntax.OpBeginText, syntax.OpEndText:
+		return true
+	}
+	return false
+}
+
+func startsWithSpace(s string) bool {
+	return strings.TrimLeftFunc(s, unicode.IsSpace) != s
+}
+
+func endsWithSpace(s string) bool {
+	return strings.TrimRightFunc(s, unicode.IsSpace) != s
+}
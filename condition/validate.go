@@ -0,0 +1,288 @@
+/**
+ * Copyright (c) 2019-present Future Corporation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package condition
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Problem is a mistake of the config file. Line is 0 if the position is unknown.
+type Problem struct {
+	File string
+	Line int
+	Path string // e.g. "search_list[1].queries[0]"
+	Msg  string
+}
+
+func (p Problem) String() string {
+	pos := p.File
+	if p.Line > 0 {
+		pos = fmt.Sprintf("%v:%d", p.File, p.Line)
+	}
+	if p.Path == "" {
+		return fmt.Sprintf("%v: %v", pos, p.Msg)
+	}
+	return fmt.Sprintf("%v: %v: %v", pos, p.Path, p.Msg)
+}
+
+// ValidateFile strictly decodes the config file. Unlike LoadFile, all problems are reported at once
+// including unknown fields, which are silently ignored by LoadFile.
+// The returned options are valid only if there are no problems.
+func ValidateFile(path string) (Options, []Problem, error) {
+	src, err := ioutil.ReadFile(path)
+	if err != nil {
+		return Options{}, nil, err
+	}
+	jsonBytes, err := ToJSON(path, src)
+	if err == nil {
+		jsonBytes, err = ExpandEnv(jsonBytes)
+	}
+	if err != nil {
+		return Options{}, []Problem{{File: path, Msg: err.Error()}}, nil
+	}
+
+	var raw interface{}
+	if err := json.Unmarshal(jsonBytes, &raw); err != nil {
+		return Options{}, []Problem{{File: path, Msg: err.Error()}}, nil
+	}
+
+	v := &validator{file: path, src: string(src)}
+	v.walk(raw, reflect.TypeOf(Options{}), nil)
+	v.check(raw)
+
+	var ops Options
+	if err := json.Unmarshal(jsonBytes, &ops); err != nil && len(v.problems) == 0 {
+		v.problems = append(v.problems, Problem{File: path, Msg: err.Error()})
+	}
+	sort.SliceStable(v.problems, func(i, j int) bool {
+		return v.problems[i].Line < v.problems[j].Line
+	})
+	return ops, v.problems, nil
+}
+
+type validator struct {
+	file     string
+	src      string
+	problems []Problem
+}
+
+var unmarshalerType = reflect.TypeOf((*json.Unmarshaler)(nil)).Elem()
+
+// walk compares the decoded value with the type, field by field.
+func (v *validator) walk(value interface{}, t reflect.Type, path []string) {
+	if value == nil {
+		return
+	}
+	if t.Kind() == reflect.Struct {
+		obj, ok := value.(map[string]interface{})
+		if !ok {
+			v.report(path, "", "must be an object")
+			return
+		}
+		var keys []string
+		for key := range obj {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			child := obj[key]
+			f, ok := fieldByTag(t, key)
+			if !ok {
+				v.report(append(path, key), "", "unknown field")
+				continue
+			}
+			v.walk(child, f.Type, append(path, key))
+		}
+		return
+	}
+	if t.Kind() == reflect.Slice && !reflect.PtrTo(t).Implements(unmarshalerType) {
+		arr, ok := value.([]interface{})
+		if !ok {
+			v.report(path, "", "must be an array")
+			return
+		}
+		for i, child := range arr {
+			v.walk(child, t.Elem(), append(path, strconv.Itoa(i)))
+		}
+		return
+	}
+
+	// a leaf is decoded alone, so that the validation of Sentence and the type errors are reported with the position
+	b, err := json.Marshal(value)
+	if err != nil {
+		v.report(path, "", err.Error())
+		return
+	}
+	if err := json.Unmarshal(b, reflect.New(t).Interface()); err != nil {
+		s, _ := value.(string)
+		if _, ok := err.(*json.UnmarshalTypeError); ok {
+			err = fmt.Errorf("must be %v", t.Kind())
+		}
+		v.report(path, s, err.Error())
+	}
+}
+
+// check reports the mistakes that are valid as JSON but fail at runtime.
+// Each search is decoded alone, so that a broken search does not hide the mistakes of the others.
+func (v *validator) check(raw interface{}) {
+	obj, _ := raw.(map[string]interface{})
+	list, _ := obj["search_list"].([]interface{})
+	if len(list) == 0 {
+		v.report([]string{"search_list"}, "", "at least one search is required")
+	}
+	for i, elem := range list {
+		b, err := json.Marshal(elem)
+		if err != nil {
+			continue
+		}
+		var s Search
+		if err := json.Unmarshal(b, &s); err != nil {
+			// already reported by walk
+			continue
+		}
+		v.checkSearch([]string{"search_list", strconv.Itoa(i)}, s)
+	}
+}
+
+func (v *validator) checkSearch(path []string, s Search) {
+	if s.Provider != "" && s.Provider != ProviderGitHub && s.Provider != ProviderGitLab {
+		v.report(append(path, "provider"), s.Provider, fmt.Sprintf("unknown provider %q", s.Provider))
+	}
+	if len(s.QueryList) == 0 {
+		v.report(append(path, "queries"), "", "at least one query is required")
+	}
+	for j, q := range s.QueryList {
+		if q.IsPattern() {
+			continue
+		}
+		if msg := checkBraces(string(q)); msg != "" {
+			v.report(append(path, "queries", strconv.Itoa(j)), string(q), msg)
+		}
+	}
+}
+
+// checkBraces reports a malformed brace expression, which is searched literally without expansion.
+func checkBraces(s string) string {
+	depth := 0
+	for _, c := range s {
+		switch c {
+		case '{':
+			depth++
+		case '}':
+			depth--
+			if depth < 0 {
+				return "unbalanced brace: unexpected \"}\""
+			}
+		}
+	}
+	if depth > 0 {
+		return "unbalanced brace: missing \"}\""
+	}
+	if strings.Contains(s, "{}") {
+		return "empty brace"
+	}
+	return ""
+}
+
+func fieldByTag(t reflect.Type, key string) (reflect.StructField, bool) {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if strings.Split(f.Tag.Get("json"), ",")[0] == key {
+			return f, true
+		}
+	}
+	return reflect.StructField{}, false
+}
+
+func (v *validator) report(path []string, value, msg string) {
+	v.problems = append(v.problems, Problem{
+		File: v.file,
+		Line: lineOf(v.src, path, value),
+		Path: formatPath(path),
+		Msg:  msg,
+	})
+}
+
+func formatPath(path []string) string {
+	var b strings.Builder
+	for _, p := range path {
+		if _, err := strconv.Atoi(p); err == nil {
+			b.WriteString("[" + p + "]")
+			continue
+		}
+		if b.Len() > 0 {
+			b.WriteString(".")
+		}
+		b.WriteString(p)
+	}
+	return b.String()
+}
+
+// lineOf finds the line of the path by scanning the source for the keys in order, because the decoders do not keep positions.
+// It is a best effort: an element of an array is found by the n-th occurrence of the next key.
+func lineOf(src string, path []string, value string) int {
+	cursor, found := 0, false
+	for i := 0; i < len(path); i++ {
+		n, err := strconv.Atoi(path[i])
+		if err != nil {
+			pos := indexesOfKey(src[cursor:], path[i])
+			if len(pos) == 0 {
+				break
+			}
+			cursor, found = cursor+pos[0], true
+			continue
+		}
+		if i+1 == len(path) {
+			break
+		}
+		// skip the keys of the previous elements
+		pos := indexesOfKey(src[cursor:], path[i+1])
+		if len(pos) == 0 {
+			break
+		}
+		if n >= len(pos) {
+			n = len(pos) - 1
+		}
+		cursor, found = cursor+pos[n], true
+		i++
+	}
+	if value != "" {
+		if pos := strings.Index(src[cursor:], value); pos != -1 {
+			cursor, found = cursor+pos, true
+		}
+	}
+	if !found {
+		return 0
+	}
+	return strings.Count(src[:cursor], "\n") + 1
+}
+
+// indexesOfKey returns the positions of the key in YAML("key:"), TOML("key =", "[[key]]") or JSON("\"key\":").
+func indexesOfKey(src, key string) []int {
+	re := regexp.MustCompile(`(?m)(^|[\s{,\[])"?` + regexp.QuoteMeta(key) + `"?\s*(:|=|\]\])`)
+	var result []int
+	for _, m := range re.FindAllStringSubmatchIndex(src, -1) {
+		result = append(result, m[3])
+	}
+	return result
+}
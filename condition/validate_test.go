@@ -0,0 +1,95 @@
+/**
+ * Copyright (c) 2019-present Future Corporation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package condition
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestValidateFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "codediaper")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "config.yaml")
+	content := `github_token: dummy-token
+concurency: 3
+search_list:
+  - queries: ["Copyright+{2019,2020}+Future"]
+    skip_owners: [future-architect]
+  - queries: []
+    provider: gitlba
+    skip_owner: [ghost]
+  - queries: ["Copyright+{2019+Future"]
+  - queries: ["/[0-9]+/"]
+    concurrency: "3"
+`
+	if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	_, problems, err := ValidateFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var actual []string
+	for _, p := range problems {
+		actual = append(actual, p.String())
+	}
+	want := []string{
+		path + `:2: concurency: unknown field`,
+		path + `:6: search_list[1].queries: at least one query is required`,
+		path + `:7: search_list[1].provider: unknown provider "gitlba"`,
+		path + `:8: search_list[1].skip_owner: unknown field`,
+		path + `:9: search_list[2].queries[0]: unbalanced brace: missing "}"`,
+		path + `:10: search_list[3].queries[0]: regular expression /[0-9]+/ has no literal word to search`,
+		path + `:11: search_list[3].concurrency: unknown field`,
+	}
+	if !reflect.DeepEqual(actual, want) {
+		t.Errorf("got: %v\nwant: %v", actual, want)
+	}
+}
+
+func TestValidateFileOK(t *testing.T) {
+	dir, err := ioutil.TempDir("", "codediaper")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "config.json")
+	content := `{"concurrency": 2, "search_list": [{"queries": ["Copyright+{2019,2020}+Future"], "skip_repos": "repo1,repo2"}]}`
+	if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	ops, problems, err := ValidateFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(problems) != 0 {
+		t.Errorf("got: %v\nwant: no problems", problems)
+	}
+	if len(ops.ExpandSearch()) != 2 {
+		t.Errorf("got: %v\nwant: %v", len(ops.ExpandSearch()), 2)
+	}
+}
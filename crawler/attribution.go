@@ -0,0 +1,81 @@
+/**
+ * Copyright (c) 2019-present Future Corporation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package crawler
+
+import (
+	"context"
+	"strings"
+	"time"
+)
+
+// maxAttributionCommits is the number of commits of the file searched for the commit that introduced the matched line.
+const maxAttributionCommits = 20
+
+// Commit identifies who pushed a file and when.
+type Commit struct {
+	SHA       string    `json:"sha"`
+	URL       string    `json:"url,omitempty"`
+	Date      time.Time `json:"date"`            // committed date
+	Author    string    `json:"author"`          // "name <email>"
+	Committer string    `json:"committer"`       // "name <email>"
+	Login     string    `json:"login,omitempty"` // account of the author on the code hosting service
+}
+
+// Attribution is the history of a hit file.
+type Attribution struct {
+	LastCommit *Commit `json:"last_commit,omitempty"`
+	Introduced *Commit `json:"introduced,omitempty"` // the latest commit that added the matched line
+}
+
+// Attributor is implemented by the crawlers that can look up the commits of a hit file.
+// line is the matched line to find the commit that introduced it.
+type Attributor interface {
+	Attribute(ctx context.Context, repo Repository, file File, line string) (*Attribution, error)
+}
+
+// addedIn reports whether the diff adds a line that contains line.
+func addedIn(diff, line string) bool {
+	if line == "" {
+		return false
+	}
+	for _, v := range strings.Split(diff, "\n") {
+		if strings.HasPrefix(v, "+") && !strings.HasPrefix(v, "+++") && strings.Contains(v[1:], line) {
+			return true
+		}
+	}
+	return false
+}
+
+// identity formats the name and the email like git.
+func identity(name, email string) string {
+	if email == "" {
+		return name
+	}
+	return name + " <" + email + ">"
+}
+
+// blobRef returns the commit or branch in the URL of a file such as "https://github.com/owner/repo/blob/<ref>/path".
+func blobRef(fileURL string) string {
+	i := strings.Index(fileURL, "/blob/")
+	if i < 0 {
+		return ""
+	}
+	rest := fileURL[i+len("/blob/"):]
+	if j := strings.Index(rest, "/"); j >= 0 {
+		return rest[:j]
+	}
+	return rest
+}
@@ -0,0 +1,190 @@
+/**
+ * Copyright (c) 2019-present Future Corporation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package crawler
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+)
+
+func TestAddedIn(t *testing.T) {
+	diff := "@@ -1,2 +1,2 @@\n package a\n-// Copyright 2019 Example\n+// Copyright 2019 Future"
+	for _, c := range []struct {
+		line     string
+		expected bool
+	}{
+		{"Copyright 2019 Future", true},
+		{"Copyright 2019 Example", false},
+		{"package a", false},
+		{"", false},
+	} {
+		if actual := addedIn(diff, c.line); actual != c.expected {
+			t.Errorf("%v got: %v\nwant: %v", c.line, actual, c.expected)
+		}
+	}
+}
+
+func TestBlobRef(t *testing.T) {
+	for input, expected := range map[string]string{
+		"https://github.com/ghost/leak/blob/3f2a1b/src/a.go": "3f2a1b",
+		"https://github.com/ghost/leak/blob/master":          "master",
+		"src/a.go": "",
+	} {
+		if actual := blobRef(input); actual != expected {
+			t.Errorf("got: %v\nwant: %v", actual, expected)
+		}
+	}
+}
+
+func TestGitHubAttribute(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/v3/repos/ghost/leak/commits", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("sha") != "3f2a1b" || r.URL.Query().Get("path") != "src/a.go" {
+			t.Errorf("got: %v", r.URL.RawQuery)
+		}
+		fmt.Fprint(w, `[
+			{"sha": "ccc", "html_url": "https://ghe.example.com/ghost/leak/commit/ccc",
+			 "commit": {"author": {"name": "Ghost", "email": "ghost@example.com"}, "committer": {"name": "Ghost", "email": "ghost@example.com", "date": "2019-08-02T09:00:00Z"}},
+			 "author": {"login": "ghost"}},
+			{"sha": "bbb", "html_url": "https://ghe.example.com/ghost/leak/commit/bbb",
+			 "commit": {"author": {"name": "Taro", "email": "taro@example.com"}, "committer": {"name": "Taro", "email": "taro@example.com", "date": "2019-08-01T09:00:00Z"}},
+			 "author": {"login": "taro"}}
+		]`)
+	})
+	mux.HandleFunc("/api/v3/repos/ghost/leak/commits/ccc", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"sha": "ccc", "files": [{"filename": "src/a.go", "patch": "@@ -1 +1 @@\n-func A() {}\n+func A() int {}"}]}`)
+	})
+	mux.HandleFunc("/api/v3/repos/ghost/leak/commits/bbb", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"sha": "bbb", "files": [{"filename": "src/a.go", "patch": "@@ -0,0 +1,2 @@\n+// Copyright 2019 Future\n+func A() {}"}]}`)
+	})
+	ts := httptest.NewServer(mux)
+	defer ts.Close()
+
+	c, err := NewGitHubEnterpriseCrawler("dummy-token", ts.URL+"/api/v3/", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.(*gitHubCrawler).limits = newUnlimited()
+
+	file := File{URL: "https://ghe.example.com/ghost/leak/blob/3f2a1b/src/a.go", Path: "src/a.go"}
+	actual, err := c.(Attributor).Attribute(context.Background(), Repository{Owner: "ghost", Name: "leak"}, file, "Copyright 2019 Future")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if actual.LastCommit == nil || actual.LastCommit.SHA != "ccc" || actual.LastCommit.Login != "ghost" {
+		t.Errorf("got: %+v", actual.LastCommit)
+	}
+	if actual.Introduced == nil || actual.Introduced.SHA != "bbb" || actual.Introduced.Author != "Taro <taro@example.com>" {
+		t.Errorf("got: %+v", actual.Introduced)
+	}
+}
+
+func TestGitLabAttribute(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.EscapedPath() {
+		case "/api/v4/projects/ghost%2Fleak/repository/commits":
+			if r.URL.Query().Get("ref_name") != "master" || r.URL.Query().Get("path") != "src/a.go" {
+				t.Errorf("got: %v", r.URL.RawQuery)
+			}
+			fmt.Fprint(w, `[
+				{"id": "ccc", "author_name": "Ghost", "author_email": "ghost@example.com", "committed_date": "2019-08-02T09:00:00Z"},
+				{"id": "bbb", "author_name": "Taro", "author_email": "taro@example.com", "committed_date": "2019-08-01T09:00:00Z",
+				 "web_url": "https://gitlab.example.com/ghost/leak/-/commit/bbb"}
+			]`)
+		case "/api/v4/projects/ghost%2Fleak/repository/commits/ccc/diff":
+			fmt.Fprint(w, `[{"new_path": "README.md", "diff": "+Copyright 2019 Future"}]`)
+		case "/api/v4/projects/ghost%2Fleak/repository/commits/bbb/diff":
+			fmt.Fprint(w, `[{"new_path": "src/a.go", "diff": "@@ -0,0 +1 @@\n+// Copyright 2019 Future"}]`)
+		default:
+			t.Errorf("unexpected path: %v", r.URL.EscapedPath())
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer ts.Close()
+
+	c, err := NewGitLabCrawler(ts.URL+"/api/v4", "dummy-token")
+	if err != nil {
+		t.Fatal(err)
+	}
+	file := File{Path: "src/a.go", Ref: "master"}
+	actual, err := c.(Attributor).Attribute(context.Background(), Repository{Owner: "ghost", Name: "leak"}, file, "Copyright 2019 Future")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if actual.LastCommit == nil || actual.LastCommit.SHA != "ccc" {
+		t.Errorf("got: %+v", actual.LastCommit)
+	}
+	if actual.Introduced == nil || actual.Introduced.SHA != "bbb" || actual.Introduced.URL == "" {
+		t.Errorf("got: %+v", actual.Introduced)
+	}
+}
+
+func TestLocalAttribute(t *testing.T) {
+	dir, err := ioutil.TempDir("", "codediaper")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	commit := func(name, content, author string) {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		for _, args := range [][]string{
+			{"add", name},
+			{"-c", "user.name=" + author, "-c", "user.email=" + author + "@example.com", "commit", "-q", "-m", name},
+		} {
+			if out, err := exec.Command("git", append([]string{"-C", dir}, args...)...).CombinedOutput(); err != nil {
+				t.Fatalf("%v: %s", err, out)
+			}
+		}
+	}
+	if out, err := exec.Command("git", "init", "-q", dir).CombinedOutput(); err != nil {
+		t.Skipf("git is not available: %v %s", err, out)
+	}
+	commit("a.go", "package a\n// Copyright 2019 Future\n", "taro")
+	commit("a.go", "package a\n// Copyright 2019 Future\nfunc A() {}\n", "ghost")
+
+	c, err := NewLocalCrawler(dir, false, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	actual, err := c.(Attributor).Attribute(context.Background(), Repository{}, File{URL: "a.go"}, "Copyright 2019 Future")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if actual.LastCommit == nil || actual.LastCommit.Author != "ghost <ghost@example.com>" {
+		t.Errorf("got: %+v", actual.LastCommit)
+	}
+	if actual.Introduced == nil || actual.Introduced.Author != "taro <taro@example.com>" {
+		t.Errorf("got: %+v", actual.Introduced)
+	}
+
+	untracked, err := c.(Attributor).Attribute(context.Background(), Repository{}, File{URL: "b.go"}, "Copyright")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if untracked.LastCommit != nil || untracked.Introduced != nil {
+		t.Errorf("got: %+v", untracked)
+	}
+}
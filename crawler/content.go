@@ -0,0 +1,77 @@
+/**
+ * Copyright (c) 2019-present Future Corporation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package crawler
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+// ErrTooLarge is returned by FetchContent if the content exceeds the size limit.
+var ErrTooLarge = errors.New("content is too large")
+
+// ContentFetcher is implemented by the crawlers that can download the whole content of a hit file.
+type ContentFetcher interface {
+	FetchContent(ctx context.Context, repo Repository, file File, maxSize int) ([]byte, error)
+}
+
+// maxCacheSize is the total byte size of the contents kept by the blob cache.
+const maxCacheSize = 64 * 1024 * 1024
+
+// blobCache keeps the downloaded contents by the blob key, so that a file hit by multiple queries
+// or shared by forks is downloaded once in a run.
+type blobCache struct {
+	mu    sync.Mutex
+	blobs map[string][]byte
+	size  int
+}
+
+var sharedBlobCache = &blobCache{blobs: map[string][]byte{}}
+
+func (c *blobCache) get(key string) ([]byte, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	b, ok := c.blobs[key]
+	return b, ok
+}
+
+// put keeps the content unless the cache is full.
+func (c *blobCache) put(key string, b []byte) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, ok := c.blobs[key]; ok || c.size+len(b) > maxCacheSize {
+		return
+	}
+	c.blobs[key] = b
+	c.size += len(b)
+}
+
+// limitWriter fails when more than max bytes are written, so that a large content is not downloaded to the end.
+type limitWriter struct {
+	buf      []byte
+	max      int
+	exceeded bool
+}
+
+func (w *limitWriter) Write(p []byte) (int, error) {
+	if len(w.buf)+len(p) > w.max {
+		w.exceeded = true
+		return 0, ErrTooLarge
+	}
+	w.buf = append(w.buf, p...)
+	return len(p), nil
+}
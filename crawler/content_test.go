@@ -0,0 +1,104 @@
+/**
+ * Copyright (c) 2019-present Future Corporation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package crawler
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestGitHubFetchContent(t *testing.T) {
+	var calls int
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/v3/repos/ghost/leak/git/blobs/", func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if r.Header.Get("Accept") != "application/vnd.github.v3.raw" {
+			t.Errorf("got: %v", r.Header.Get("Accept"))
+		}
+		switch r.URL.Path {
+		case "/api/v3/repos/ghost/leak/git/blobs/aaa":
+			fmt.Fprint(w, "package a\n// Copyright 2019 Example\n")
+		case "/api/v3/repos/ghost/leak/git/blobs/bbb":
+			fmt.Fprint(w, strings.Repeat("a", 100))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	})
+	ts := httptest.NewServer(mux)
+	defer ts.Close()
+
+	c, err := NewGitHubEnterpriseCrawler("dummy-token", ts.URL+"/api/v3/", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	fetcher := c.(ContentFetcher)
+	repo := Repository{Owner: "ghost", Name: "leak"}
+
+	for i := 0; i < 2; i++ {
+		b, err := fetcher.FetchContent(context.Background(), repo, File{Ref: "aaa"}, 50)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(b) != "package a\n// Copyright 2019 Example\n" {
+			t.Errorf("got: %q", b)
+		}
+	}
+	if calls != 1 {
+		t.Errorf("got: %v\nwant: %v (cached)", calls, 1)
+	}
+
+	if _, err := fetcher.FetchContent(context.Background(), repo, File{Ref: "bbb"}, 50); err != ErrTooLarge {
+		t.Errorf("got: %v\nwant: %v", err, ErrTooLarge)
+	}
+	if _, err := fetcher.FetchContent(context.Background(), repo, File{Ref: "ccc"}, 50); err == nil {
+		t.Errorf("got: nil\nwant: error")
+	}
+}
+
+func TestGitLabFetchContent(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.EscapedPath() != "/api/v4/projects/ghost%2Fleak/repository/files/src%2Fa.go/raw" {
+			t.Errorf("unexpected path: %v", r.URL.EscapedPath())
+		}
+		if r.URL.Query().Get("ref") != "master" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		fmt.Fprint(w, "// Copyright 2019 Example\n")
+	}))
+	defer ts.Close()
+
+	c, err := NewGitLabCrawler(ts.URL+"/api/v4", "dummy-token")
+	if err != nil {
+		t.Fatal(err)
+	}
+	file := File{Path: "src/a.go", Ref: "master"}
+	b, err := c.(ContentFetcher).FetchContent(context.Background(), Repository{Owner: "ghost", Name: "leak"}, file, 1024)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != "// Copyright 2019 Example\n" {
+		t.Errorf("got: %q", b)
+	}
+
+	if _, err := c.(ContentFetcher).FetchContent(context.Background(), Repository{Owner: "ghost", Name: "leak"}, File{Path: "src/a.go", Ref: "dev"}, 10); err == nil {
+		t.Errorf("got: nil\nwant: error")
+	}
+}
@@ -17,34 +17,114 @@ package crawler
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"github.com/google/go-github/github"
 	"golang.org/x/oauth2"
+	"log"
+	"net/http"
 	"reflect"
 	"strings"
+	"sync"
 	"time"
 )
 
-const MaxPageSize = 100
+const (
+	MaxPageSize = 100
+
+	// MaxSearchResults is the number of results GitHub code search can return for one query.
+	MaxSearchResults = 1000
+
+	// MaxIndexedFileSize is the byte size limit of files indexed by GitHub code search.
+	MaxIndexedFileSize = 384 * 1024
+)
+
+var errTooManyResults = errors.New("too many results for one query")
 
 type Crawler interface {
-	Search(ctx context.Context, words []string) (Repositories, error)
-	FulfillForkSource(ctx context.Context, repos Repositories) (Repositories, error)
+	Search(ctx context.Context, words []string) (Repositories, Coverage, error)
+	// Enrich fills the fork source and the metadata of the repositories.
+	Enrich(ctx context.Context, repos Repositories) (Repositories, error)
 }
 
+// enrichConcurrency is the number of goroutines to fetch repositories.
+const enrichConcurrency = 4
+
 type gitHubCrawler struct {
-	client *github.Client
-	option *github.SearchOptions
+	client     *github.Client
+	option     *github.SearchOptions
+	limits     *GitHubRateLimits
+	maxResults int
+}
+
+// GitHubRateLimits is the API budget of one token on one host.
+// Search API and the other APIs(core) have separate limits.
+// https://developer.github.com/v3/search/#rate-limit
+type GitHubRateLimits struct {
+	Search *RateLimiter
+	Core   *RateLimiter
+}
+
+var (
+	sharedGitHubRateLimitsMu sync.Mutex
+	sharedGitHubRateLimits   = map[string]*GitHubRateLimits{}
+)
+
+// SharedGitHubRateLimits returns the limits shared by all crawlers that use the token on the host.
+func SharedGitHubRateLimits(baseURL, token string) *GitHubRateLimits {
+	sharedGitHubRateLimitsMu.Lock()
+	defer sharedGitHubRateLimitsMu.Unlock()
+
+	key := baseURL + "\n" + token
+	if l, ok := sharedGitHubRateLimits[key]; ok {
+		return l
+	}
+	l := &GitHubRateLimits{
+		Search: NewRateLimiter(30, time.Minute),
+		Core:   NewRateLimiter(5000, time.Hour),
+	}
+	sharedGitHubRateLimits[key] = l
+	return l
 }
 
 func NewGitHubCrawler(token string) Crawler {
+	client := github.NewClient(newTokenClient(token))
+	return newGitHubCrawler(client, SharedGitHubRateLimits(client.BaseURL.String(), token))
+}
 
-	tokenClient := oauth2.NewClient(context.Background(), oauth2.StaticTokenSource(&oauth2.Token{
+// NewGitHubEnterpriseCrawler returns a crawler for GitHub Enterprise Server.
+// baseURL is the API endpoint such as "https://github.example.com/api/v3/".
+// If uploadURL is empty then baseURL is used.
+func NewGitHubEnterpriseCrawler(token, baseURL, uploadURL string) (Crawler, error) {
+	client, err := NewGitHubClient(token, baseURL, uploadURL)
+	if err != nil {
+		return nil, err
+	}
+	return newGitHubCrawler(client, SharedGitHubRateLimits(client.BaseURL.String(), token)), nil
+}
+
+// NewGitHubClient returns the API client authorized by the token. If baseURL is empty then github.com is used.
+// If uploadURL is empty then baseURL is used.
+func NewGitHubClient(token, baseURL, uploadURL string) (*github.Client, error) {
+	if baseURL == "" {
+		return github.NewClient(newTokenClient(token)), nil
+	}
+	if uploadURL == "" {
+		uploadURL = baseURL
+	}
+	return github.NewEnterpriseClient(baseURL, uploadURL, newTokenClient(token))
+}
+
+func newTokenClient(token string) *http.Client {
+	return oauth2.NewClient(context.Background(), oauth2.StaticTokenSource(&oauth2.Token{
 		AccessToken: token,
 	}))
+}
 
+func newGitHubCrawler(client *github.Client, limits *GitHubRateLimits) *gitHubCrawler {
 	return &gitHubCrawler{
-		client: github.NewClient(tokenClient),
+		client: client,
+		limits: limits,
 		option: &github.SearchOptions{
 			Sort:  "updated",
 			Order: "desc",
@@ -53,53 +133,120 @@ func NewGitHubCrawler(token string) Crawler {
 			},
 			TextMatch: true,
 		},
+		maxResults: MaxSearchResults,
 	}
 }
 
 // According to the API reference, up to 100 can be obtained with one API call
 // https://developer.github.com/v3/search/
 // https://developer.github.com/v3/search/#constructing-a-search-query
-func (c *gitHubCrawler) Search(ctx context.Context, words []string) (Repositories, error) {
+//
+// GitHub code search never returns more than 1000 results for one query.
+// When the hits exceed it, the query is split into disjoint shards by "size:" qualifier until each shard fits.
+func (c *gitHubCrawler) Search(ctx context.Context, words []string) (Repositories, Coverage, error) {
+	q := strings.Join(words, "+") + "+in:file"
+	if strings.Contains(q, "size:") {
+		// already narrowed down by the user, so it can not be split by size
+		return c.searchAll(ctx, q, false)
+	}
+	return c.searchShard(ctx, q, 0, MaxIndexedFileSize)
+}
+
+func (c *gitHubCrawler) searchShard(ctx context.Context, q string, min, max int) (Repositories, Coverage, error) {
+	shardQuery := q
+	if min != 0 || max != MaxIndexedFileSize {
+		shardQuery = fmt.Sprintf("%s+size:%d..%d", q, min, max)
+	}
+
+	result, coverage, err := c.searchAll(ctx, shardQuery, min < max)
+	if err != errTooManyResults {
+		return result, coverage, err
+	}
+
+	mid := min + (max-min)/2
+	log.Printf("%+v Hits exceed the limit. Split into size:%d..%d and size:%d..%d\n", coverage.Total, min, mid, mid+1, max)
+
+	lower, lowerCoverage, err := c.searchShard(ctx, q, min, mid)
+	if err != nil {
+		return nil, coverage, err
+	}
+	upper, upperCoverage, err := c.searchShard(ctx, q, mid+1, max)
+	if err != nil {
+		return nil, coverage, err
+	}
+
+	result = lower
+	for _, r := range upper {
+		result = result.Merge(r)
+	}
+	coverage.Fetched = lowerCoverage.Fetched + upperCoverage.Fetched
+	coverage.Incomplete = lowerCoverage.Incomplete || upperCoverage.Incomplete
+	return result, coverage, nil
+}
+
+// searchAll fetches all pages of the query. If splittable is true and the hits exceed the limit,
+// it returns errTooManyResults right after the first API call.
+func (c *gitHubCrawler) searchAll(ctx context.Context, q string, splittable bool) (Repositories, Coverage, error) {
 
 	result := Repositories{}
+	coverage := Coverage{}
+
+	option := *c.option
 
 	apiCallCnt := 0
 	for {
-		q := strings.Join(words, "+")
-		codeSearchResult, resp, err := c.client.Search.Code(ctx, q+"+in:file", c.option)
+		if err := c.limits.Search.Wait(ctx); err != nil {
+			return nil, coverage, err
+		}
+		codeSearchResult, resp, err := c.client.Search.Code(ctx, q, &option)
+		c.updateRate(c.limits.Search, resp)
 		if abuseRateLimitErr, ok := err.(*github.AbuseRateLimitError); ok {
 
 			// The time at which the current rate limit window resets in UTC epoch seconds.
 			// https://developer.github.com/v3/#rate-limiting
-			fmt.Printf("retry after %v\n", *abuseRateLimitErr.RetryAfter)
-			time.Sleep(*abuseRateLimitErr.RetryAfter)
+			log.Printf("retry after %v\n", retryAfter(abuseRateLimitErr))
+			c.limits.Search.Pause(retryAfter(abuseRateLimitErr))
 			continue
 
 		} else if _, ok := err.(*github.RateLimitError); ok {
-			fmt.Printf("RateLimit Exceed\n")
+			log.Printf("RateLimit Exceed\n")
+			coverage.Incomplete = true
 			break
 		} else if err != nil {
-			fmt.Printf("Something happend: %+v \n type: %+v\n", err.Error(), reflect.TypeOf(err))
-			return nil, err
+			log.Printf("Something happend: %+v \n type: %+v\n", err.Error(), reflect.TypeOf(err))
+			return nil, coverage, err
 		}
 
 		if apiCallCnt == 0 {
-			fmt.Printf("%+v Hits. Continue searching\n", *codeSearchResult.Total)
+			coverage.Total = codeSearchResult.GetTotal()
+			if splittable && coverage.Total > c.maxResults {
+				return nil, coverage, errTooManyResults
+			}
+			log.Printf("%+v Hits. Continue searching: %v\n", coverage.Total, q)
 		}
 		apiCallCnt++
 
+		if codeSearchResult.GetIncompleteResults() {
+			// search timed out on GitHub side
+			coverage.Incomplete = true
+		}
+		coverage.Fetched += len(codeSearchResult.CodeResults)
+
 		for _, cr := range codeSearchResult.CodeResults {
 			files := make(Files, 0, len(cr.TextMatches))
 			for _, match := range cr.TextMatches {
 				f := File{
 					Fragments: []string{match.GetFragment()},
 					URL:       cr.GetHTMLURL(),
+					Path:      cr.GetPath(),
+					Ref:       cr.GetSHA(),
 				}
 				files = files.Merge(f)
 			}
 
 			r := Repository{
 				URL:      *cr.Repository.HTMLURL,
+				Host:     HostOf(*cr.Repository.HTMLURL),
 				Owner:    strings.Split(*cr.Repository.FullName, "/")[0],
 				Name:     strings.Split(*cr.Repository.FullName, "/")[1],
 				HitFiles: files,
@@ -113,51 +260,208 @@ func (c *gitHubCrawler) Search(ctx context.Context, words []string) (Repositorie
 		}
 
 		// update search condition
-		c.option.Page = resp.NextPage
+		option.Page = resp.NextPage
+	}
 
-		time.Sleep(1000 * time.Millisecond)
+	if coverage.Total > c.maxResults {
+		coverage.Incomplete = true
 	}
 
-	return result, nil
+	return result, coverage, nil
 }
 
-func (c *gitHubCrawler) FulfillForkSource(ctx context.Context, repos Repositories) (Repositories, error) {
+// Enrich fetches the repositories in parallel. The requests share the core API budget.
+func (c *gitHubCrawler) Enrich(ctx context.Context, repos Repositories) (Repositories, error) {
 
-	var result Repositories
-	for _, v := range repos {
-		source, err := c.fetchForkSource(ctx, v.Owner, v.Name)
+	result := make(Repositories, len(repos))
+	errs := make([]error, len(repos))
+
+	sem := make(chan struct{}, enrichConcurrency)
+	var wg sync.WaitGroup
+	for i, v := range repos {
+		wg.Add(1)
+		go func(i int, v Repository) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			result[i], errs[i] = c.enrich(ctx, v)
+		}(i, v)
+	}
+	wg.Wait()
+
+	for _, err := range errs {
 		if err != nil {
 			return nil, err
 		}
-		v.ForkSource = source
-
-		result = append(result, v)
 	}
 	return result, nil
 }
 
-func (c *gitHubCrawler) fetchForkSource(ctx context.Context, owner, repoName string) (string, error) {
+func (c *gitHubCrawler) enrich(ctx context.Context, r Repository) (Repository, error) {
 	for {
-		repo, _, err := c.client.Repositories.Get(ctx, owner, repoName)
+		if err := c.limits.Core.Wait(ctx); err != nil {
+			return r, err
+		}
+		repo, resp, err := c.client.Repositories.Get(ctx, r.Owner, r.Name)
+		c.updateRate(c.limits.Core, resp)
 
 		if abuseRateLimitErr, ok := err.(*github.AbuseRateLimitError); ok {
 			// The time at which the current rate limit window resets in UTC epoch seconds.
 			// https://developer.github.com/v3/#rate-limiting
-			time.Sleep(*abuseRateLimitErr.RetryAfter)
+			c.limits.Core.Pause(retryAfter(abuseRateLimitErr))
 			continue
 		} else if _, ok := err.(*github.RateLimitError); ok {
-			fmt.Printf("RateLimit Exceed\n")
-			break
+			log.Printf("RateLimit Exceed\n")
+			return r, nil
 		} else if err != nil {
-			fmt.Printf("something happend: %+v \n type: %+v\n", err.Error(), reflect.TypeOf(err))
-			return "", err
+			log.Printf("something happend: %+v \n type: %+v\n", err.Error(), reflect.TypeOf(err))
+			return r, err
+		}
+
+		if repo.Source != nil {
+			r.ForkSource = repo.Source.GetFullName()
+		}
+		r.CreatedAt = repo.GetCreatedAt().Time
+		r.PushedAt = repo.GetPushedAt().Time
+		r.Stars = repo.GetStargazersCount()
+		r.Forks = repo.GetForksCount()
+		r.DefaultBranch = repo.GetDefaultBranch()
+		r.Archived = repo.GetArchived()
+		r.Language = repo.GetLanguage()
+		return r, nil
+	}
+}
+
+// FetchContent downloads the blob of the file by its SHA. The request shares the core API budget.
+func (c *gitHubCrawler) FetchContent(ctx context.Context, repo Repository, file File, maxSize int) ([]byte, error) {
+	if file.Ref == "" {
+		return nil, fmt.Errorf("blob SHA is unknown: %v", file.URL)
+	}
+	key := c.client.BaseURL.Host + ":" + file.Ref
+	if b, ok := sharedBlobCache.get(key); ok {
+		return b, nil
+	}
+
+	req, err := c.client.NewRequest(http.MethodGet, fmt.Sprintf("repos/%v/%v/git/blobs/%v", repo.Owner, repo.Name, file.Ref), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/vnd.github.v3.raw")
+
+	for {
+		if err := c.limits.Core.Wait(ctx); err != nil {
+			return nil, err
+		}
+		w := &limitWriter{max: maxSize}
+		resp, err := c.client.Do(ctx, req, w)
+		c.updateRate(c.limits.Core, resp)
+
+		if abuseRateLimitErr, ok := err.(*github.AbuseRateLimitError); ok {
+			c.limits.Core.Pause(retryAfter(abuseRateLimitErr))
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+		if w.exceeded {
+			return nil, ErrTooLarge
+		}
+
+		sharedBlobCache.put(key, w.buf)
+		return w.buf, nil
+	}
+}
+
+// Attribute looks up the commits of the file with the commits API. The requests share the core API budget.
+// The history is as of the commit in the URL of the search result.
+func (c *gitHubCrawler) Attribute(ctx context.Context, repo Repository, file File, line string) (*Attribution, error) {
+	if file.Path == "" {
+		return nil, fmt.Errorf("path is unknown: %v", file.URL)
+	}
+
+	var commits []*github.RepositoryCommit
+	err := c.callCore(ctx, func() (*github.Response, error) {
+		var resp *github.Response
+		var err error
+		commits, resp, err = c.client.Repositories.ListCommits(ctx, repo.Owner, repo.Name, &github.CommitsListOptions{
+			SHA:         blobRef(file.URL),
+			Path:        file.Path,
+			ListOptions: github.ListOptions{PerPage: maxAttributionCommits},
+		})
+		return resp, err
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(commits) == 0 {
+		return &Attribution{}, nil
+	}
+
+	result := &Attribution{LastCommit: gitHubCommit(commits[0])}
+	for _, v := range commits {
+		var detail *github.RepositoryCommit
+		err := c.callCore(ctx, func() (*github.Response, error) {
+			var resp *github.Response
+			var err error
+			detail, resp, err = c.client.Repositories.GetCommit(ctx, repo.Owner, repo.Name, v.GetSHA())
+			return resp, err
+		})
+		if err != nil {
+			return nil, err
 		}
-		if repo.Source == nil {
-			return "", nil
+		for _, f := range detail.Files {
+			if f.GetFilename() == file.Path && addedIn(f.GetPatch(), line) {
+				result.Introduced = gitHubCommit(v)
+				return result, nil
+			}
 		}
+	}
+	return result, nil
+}
 
-		return *repo.Source.FullName, nil
+// callCore calls the core API waiting for the limiter. It is retried after the abuse rate limit.
+func (c *gitHubCrawler) callCore(ctx context.Context, call func() (*github.Response, error)) error {
+	for {
+		if err := c.limits.Core.Wait(ctx); err != nil {
+			return err
+		}
+		resp, err := call()
+		c.updateRate(c.limits.Core, resp)
+		if abuseRateLimitErr, ok := err.(*github.AbuseRateLimitError); ok {
+			c.limits.Core.Pause(retryAfter(abuseRateLimitErr))
+			continue
+		}
+		return err
+	}
+}
+
+func gitHubCommit(v *github.RepositoryCommit) *Commit {
+	commit := v.GetCommit()
+	return &Commit{
+		SHA:       v.GetSHA(),
+		URL:       v.GetHTMLURL(),
+		Date:      commit.GetCommitter().GetDate(),
+		Author:    identity(commit.GetAuthor().GetName(), commit.GetAuthor().GetEmail()),
+		Committer: identity(commit.GetCommitter().GetName(), commit.GetCommitter().GetEmail()),
+		Login:     v.GetAuthor().GetLogin(),
+	}
+}
+
+// updateRate corrects the limiter by X-RateLimit-Remaining and X-RateLimit-Reset headers.
+// https://developer.github.com/v3/#rate-limiting
+func (c *gitHubCrawler) updateRate(l *RateLimiter, resp *github.Response) {
+	if resp == nil || resp.Header.Get("X-RateLimit-Remaining") == "" {
+		// e.g. rate limiting is disabled on GitHub Enterprise Server
+		return
 	}
+	l.Update(resp.Rate.Remaining, resp.Rate.Reset.Time)
+}
 
-	return "", nil
+// retryAfter returns Retry-After of the abuse rate limit. If it is not sent, wait a minute.
+// https://developer.github.com/v3/guides/best-practices-for-integrators/#dealing-with-abuse-rate-limits
+func retryAfter(err *github.AbuseRateLimitError) time.Duration {
+	if err.RetryAfter == nil {
+		return time.Minute
+	}
+	return *err.RetryAfter
 }
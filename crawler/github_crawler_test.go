@@ -0,0 +1,180 @@
+/**
+ * Copyright (c) 2019-present Future Corporation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package crawler
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func newUnlimited() *GitHubRateLimits {
+	return &GitHubRateLimits{
+		Search: NewRateLimiter(1000, time.Second),
+		Core:   NewRateLimiter(1000, time.Second),
+	}
+}
+
+func TestGitHubEnterpriseSearch(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/v3/search/code", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer dummy-token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		fmt.Fprint(w, `{"total_count": 2, "items": [
+			{"html_url": "https://ghe.example.com/ghost/leak/blob/master/a.go",
+			 "repository": {"full_name": "ghost/leak", "html_url": "https://ghe.example.com/ghost/leak"},
+			 "text_matches": [{"fragment": "Copyright 2019 Example"}]},
+			{"html_url": "https://ghe.example.com/ghost/leak/blob/master/b.go",
+			 "repository": {"full_name": "ghost/leak", "html_url": "https://ghe.example.com/ghost/leak"},
+			 "text_matches": [{"fragment": "Copyright 2019 Example"}]}
+		]}`)
+	})
+	mux.HandleFunc("/api/v3/repos/ghost/leak", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"full_name": "ghost/leak", "source": {"full_name": "example/origin"},
+			"created_at": "2019-07-01T09:00:00Z", "pushed_at": "2019-08-01T09:00:00Z", "stargazers_count": 12, "forks_count": 3,
+			"default_branch": "master", "archived": true, "language": "Go"}`)
+	})
+	ts := httptest.NewServer(mux)
+	defer ts.Close()
+
+	c, err := NewGitHubEnterpriseCrawler("dummy-token", ts.URL+"/api/v3/", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	actual, coverage, err := c.Search(context.Background(), []string{"Copyright+2019+Example"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if coverage.Incomplete || coverage.Total != 2 || coverage.Fetched != 2 {
+		t.Errorf("got: %+v", coverage)
+	}
+	if len(actual) != 1 {
+		t.Fatalf("got: %v\nwant: %v", len(actual), 1)
+	}
+	if actual[0].Host != "ghe.example.com" || actual[0].Owner != "ghost" || len(actual[0].HitFiles) != 2 {
+		t.Errorf("got: %+v", actual[0])
+	}
+
+	fulfilled, err := c.Enrich(context.Background(), actual)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fulfilled[0].ForkSource != "example/origin" {
+		t.Errorf("got: %v\nwant: %v", fulfilled[0].ForkSource, "example/origin")
+	}
+	if fulfilled[0].Stars != 12 || fulfilled[0].Forks != 3 || !fulfilled[0].Archived || fulfilled[0].Language != "Go" ||
+		!fulfilled[0].PushedAt.Equal(time.Date(2019, 8, 1, 9, 0, 0, 0, time.UTC)) {
+		t.Errorf("got: %+v", fulfilled[0])
+	}
+}
+
+// newShardingStub returns a stand-in of GitHub code search that has one file for each size in sizes
+// and returns at most maxResults items like the real API.
+func newShardingStub(t *testing.T, sizes []int, maxResults int) *httptest.Server {
+	sizeQualifier := regexp.MustCompile(`size:(\d+)\.\.(\d+)`)
+
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		min, max := 0, MaxIndexedFileSize
+		if m := sizeQualifier.FindStringSubmatch(r.URL.Query().Get("q")); m != nil {
+			min, _ = strconv.Atoi(m[1])
+			max, _ = strconv.Atoi(m[2])
+		}
+
+		var items []map[string]interface{}
+		for i, size := range sizes {
+			if size < min || max < size {
+				continue
+			}
+			items = append(items, map[string]interface{}{
+				"html_url":     fmt.Sprintf("https://github.com/ghost/repo%d/blob/master/file.go", i%3),
+				"repository":   map[string]string{"full_name": fmt.Sprintf("ghost/repo%d", i%3), "html_url": fmt.Sprintf("https://github.com/ghost/repo%d", i%3)},
+				"text_matches": []map[string]string{{"fragment": fmt.Sprintf("Copyright 2019 Example %d", i)}},
+			})
+		}
+		total := len(items)
+		if len(items) > maxResults {
+			items = items[:maxResults]
+		}
+		if err := json.NewEncoder(w).Encode(map[string]interface{}{"total_count": total, "items": items}); err != nil {
+			t.Error(err)
+		}
+	}))
+}
+
+func TestGitHubSearchSharding(t *testing.T) {
+	var sizes []int
+	for i := 0; i < 25; i++ {
+		sizes = append(sizes, i*1000)
+	}
+	ts := newShardingStub(t, sizes, 10)
+	defer ts.Close()
+
+	c, err := NewGitHubEnterpriseCrawler("dummy-token", ts.URL+"/", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.(*gitHubCrawler).limits = newUnlimited()
+	c.(*gitHubCrawler).maxResults = 10
+
+	actual, coverage, err := c.Search(context.Background(), []string{"Copyright+2019+Example"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if coverage.Incomplete || coverage.Total != 25 || coverage.Fetched != 25 {
+		t.Errorf("got: %+v", coverage)
+	}
+
+	fragments := 0
+	for _, r := range actual {
+		for _, f := range r.HitFiles {
+			fragments += len(f.Fragments)
+		}
+	}
+	if len(actual) != 3 || fragments != 25 {
+		t.Errorf("got: %v repositories, %v fragments\nwant: 3 repositories, 25 fragments", len(actual), fragments)
+	}
+}
+
+func TestGitHubSearchShardingIncomplete(t *testing.T) {
+	// all files have the same size, so the query can not be split
+	sizes := make([]int, 25)
+	ts := newShardingStub(t, sizes, 10)
+	defer ts.Close()
+
+	c, err := NewGitHubEnterpriseCrawler("dummy-token", ts.URL+"/", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.(*gitHubCrawler).limits = newUnlimited()
+	c.(*gitHubCrawler).maxResults = 10
+
+	_, coverage, err := c.Search(context.Background(), []string{"Copyright+2019+Example"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !coverage.Incomplete || coverage.Total != 25 || coverage.Fetched != 10 {
+		t.Errorf("got: %+v", coverage)
+	}
+}
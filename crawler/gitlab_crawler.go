@@ -0,0 +1,408 @@
+/**
+ * Copyright (c) 2019-present Future Corporation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package crawler
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const DefaultGitLabBaseURL = "https://gitlab.com/api/v4/"
+
+const (
+	// gitLabMaxRetries is the number of retries of a rate limited request.
+	gitLabMaxRetries = 5
+	// gitLabRetryWait is the first wait of a rate limited request without Retry-After. It is doubled on each retry.
+	gitLabRetryWait = 10 * time.Second
+)
+
+type gitLabCrawler struct {
+	client     *http.Client
+	baseURL    *url.URL
+	token      string
+	interval   time.Duration
+	maxRetries int
+	retryWait  time.Duration
+	projects   map[int]*gitLabProject
+}
+
+// gitLabBlob is an element of the search API response (scope=blobs).
+// https://docs.gitlab.com/ee/api/search.html#scope-blobs
+type gitLabBlob struct {
+	Basename  string `json:"basename"`
+	Data      string `json:"data"`
+	Path      string `json:"path"`
+	Filename  string `json:"filename"`
+	Ref       string `json:"ref"`
+	Startline int    `json:"startline"`
+	ProjectID int    `json:"project_id"`
+}
+
+// https://docs.gitlab.com/ee/api/commits.html#list-repository-commits
+type gitLabCommit struct {
+	ID             string    `json:"id"`
+	AuthorName     string    `json:"author_name"`
+	AuthorEmail    string    `json:"author_email"`
+	CommitterName  string    `json:"committer_name"`
+	CommitterEmail string    `json:"committer_email"`
+	CommittedDate  time.Time `json:"committed_date"`
+	WebURL         string    `json:"web_url"`
+}
+
+// https://docs.gitlab.com/ee/api/commits.html#get-the-diff-of-a-commit
+type gitLabDiff struct {
+	Diff    string `json:"diff"`
+	NewPath string `json:"new_path"`
+}
+
+// https://docs.gitlab.com/ee/api/projects.html#get-single-project
+type gitLabProject struct {
+	ID                int    `json:"id"`
+	Path              string `json:"path"`
+	PathWithNamespace string `json:"path_with_namespace"`
+	WebURL            string `json:"web_url"`
+	Namespace         struct {
+		FullPath string `json:"full_path"`
+	} `json:"namespace"`
+	ForkedFromProject *gitLabProject `json:"forked_from_project"`
+	CreatedAt         time.Time      `json:"created_at"`
+	LastActivityAt    time.Time      `json:"last_activity_at"`
+	StarCount         int            `json:"star_count"`
+	ForksCount        int            `json:"forks_count"`
+	DefaultBranch     string         `json:"default_branch"`
+	Archived          bool           `json:"archived"`
+}
+
+// NewGitLabCrawler returns a crawler for gitlab.com or a self-hosted GitLab.
+// baseURL is the API v4 endpoint such as "https://gitlab.example.com/api/v4/". If empty, gitlab.com is used.
+func NewGitLabCrawler(baseURL, token string) (Crawler, error) {
+	if baseURL == "" {
+		baseURL = DefaultGitLabBaseURL
+	}
+	u, err := url.Parse(baseURL)
+	if err != nil {
+		return nil, err
+	}
+	if !strings.HasSuffix(u.Path, "/") {
+		u.Path += "/"
+	}
+
+	return &gitLabCrawler{
+		client:     http.DefaultClient,
+		baseURL:    u,
+		token:      token,
+		interval:   1000 * time.Millisecond,
+		maxRetries: gitLabMaxRetries,
+		retryWait:  gitLabRetryWait,
+		projects:   map[int]*gitLabProject{},
+	}, nil
+}
+
+// Search uses the blobs scope of the GitLab search API.
+// GitLab has no "+" separated syntax, so words are joined by space.
+func (c *gitLabCrawler) Search(ctx context.Context, words []string) (Repositories, Coverage, error) {
+
+	result := Repositories{}
+	coverage := Coverage{}
+
+	q := strings.Replace(strings.Join(words, " "), "+", " ", -1)
+
+	page := "1"
+	for {
+		params := url.Values{}
+		params.Set("scope", "blobs")
+		params.Set("search", q)
+		params.Set("per_page", strconv.Itoa(MaxPageSize))
+		params.Set("page", page)
+
+		var blobs []gitLabBlob
+		resp, err := c.get(ctx, "search?"+params.Encode(), &blobs)
+		if err != nil {
+			log.Printf("Something happend: %+v\n", err.Error())
+			return nil, coverage, err
+		}
+
+		if page == "1" {
+			// X-Total may be omitted for performance reasons
+			// https://docs.gitlab.com/ee/user/gitlab_com/index.html#pagination-response-headers
+			coverage.Total, _ = strconv.Atoi(resp.Header.Get("X-Total"))
+			log.Printf("%+v Hits. Continue searching\n", resp.Header.Get("X-Total"))
+		}
+		coverage.Fetched += len(blobs)
+
+		for _, b := range blobs {
+			project, err := c.fetchProject(ctx, strconv.Itoa(b.ProjectID))
+			if err != nil {
+				return nil, coverage, err
+			}
+
+			r := Repository{
+				URL:   project.WebURL,
+				Host:  HostOf(project.WebURL),
+				Owner: project.Namespace.FullPath,
+				Name:  project.Path,
+				HitFiles: Files{{
+					Fragments: []string{b.Data},
+					URL:       project.WebURL + "/-/blob/" + b.Ref + "/" + b.Path,
+					Path:      b.Path,
+					Ref:       b.Ref,
+				}},
+			}
+			result = result.Merge(r)
+		}
+
+		page = resp.Header.Get("X-Next-Page")
+		if page == "" {
+			// finish
+			break
+		}
+
+		select {
+		case <-time.After(c.interval):
+		case <-ctx.Done():
+			return nil, coverage, ctx.Err()
+		}
+	}
+
+	if coverage.Total == 0 {
+		coverage.Total = coverage.Fetched
+	}
+
+	return result, coverage, nil
+}
+
+// Enrich fills the metadata of the projects. The primary language is the one that has the largest percentage.
+func (c *gitLabCrawler) Enrich(ctx context.Context, repos Repositories) (Repositories, error) {
+
+	var result Repositories
+	for _, v := range repos {
+		id := url.PathEscape(v.Owner + "/" + v.Name)
+		project, err := c.fetchProject(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		source, err := c.fetchForkSource(ctx, v.Owner, v.Name)
+		if err != nil {
+			return nil, err
+		}
+		// https://docs.gitlab.com/ee/api/projects.html#languages
+		var languages map[string]float64
+		if _, err := c.get(ctx, "projects/"+id+"/languages", &languages); err != nil {
+			return nil, err
+		}
+
+		v.ForkSource = source
+		v.CreatedAt = project.CreatedAt
+		v.PushedAt = project.LastActivityAt
+		v.Stars = project.StarCount
+		v.Forks = project.ForksCount
+		v.DefaultBranch = project.DefaultBranch
+		v.Archived = project.Archived
+		v.Language = primaryLanguage(languages)
+
+		result = append(result, v)
+	}
+	return result, nil
+}
+
+func primaryLanguage(languages map[string]float64) string {
+	var result string
+	for name, percentage := range languages {
+		if result == "" || percentage > languages[result] || (percentage == languages[result] && name < result) {
+			result = name
+		}
+	}
+	return result
+}
+
+// fetchForkSource follows forked_from_project up to the root project so that
+// the result means the same as "source" of GitHub.
+func (c *gitLabCrawler) fetchForkSource(ctx context.Context, owner, repoName string) (string, error) {
+	project, err := c.fetchProject(ctx, url.PathEscape(owner+"/"+repoName))
+	if err != nil {
+		return "", err
+	}
+	if project.ForkedFromProject == nil {
+		return "", nil
+	}
+
+	source := project.ForkedFromProject
+	for {
+		parent, err := c.fetchProject(ctx, strconv.Itoa(source.ID))
+		if err != nil {
+			return "", err
+		}
+		if parent.ForkedFromProject == nil {
+			return parent.PathWithNamespace, nil
+		}
+		source = parent.ForkedFromProject
+	}
+}
+
+// FetchContent downloads the raw file at the ref of the search result.
+// https://docs.gitlab.com/ee/api/repository_files.html#get-raw-file-from-repository
+func (c *gitLabCrawler) FetchContent(ctx context.Context, repo Repository, file File, maxSize int) ([]byte, error) {
+	if file.Ref == "" || file.Path == "" {
+		return nil, fmt.Errorf("ref is unknown: %v", file.URL)
+	}
+	project := url.PathEscape(repo.Owner + "/" + repo.Name)
+	key := c.baseURL.Host + ":" + project + ":" + file.Ref + ":" + file.Path
+	if b, ok := sharedBlobCache.get(key); ok {
+		return b, nil
+	}
+
+	w := &limitWriter{max: maxSize}
+	path := fmt.Sprintf("projects/%v/repository/files/%v/raw?ref=%v", project, url.PathEscape(file.Path), url.QueryEscape(file.Ref))
+	if _, err := c.get(ctx, path, w); err != nil {
+		if w.exceeded {
+			return nil, ErrTooLarge
+		}
+		return nil, err
+	}
+
+	sharedBlobCache.put(key, w.buf)
+	return w.buf, nil
+}
+
+// Attribute looks up the commits of the file at the ref of the search result.
+func (c *gitLabCrawler) Attribute(ctx context.Context, repo Repository, file File, line string) (*Attribution, error) {
+	if file.Path == "" {
+		return nil, fmt.Errorf("path is unknown: %v", file.URL)
+	}
+	project := url.PathEscape(repo.Owner + "/" + repo.Name)
+
+	params := url.Values{}
+	params.Set("path", file.Path)
+	params.Set("per_page", strconv.Itoa(maxAttributionCommits))
+	if file.Ref != "" {
+		params.Set("ref_name", file.Ref)
+	}
+	var commits []gitLabCommit
+	if _, err := c.get(ctx, fmt.Sprintf("projects/%v/repository/commits?%v", project, params.Encode()), &commits); err != nil {
+		return nil, err
+	}
+	if len(commits) == 0 {
+		return &Attribution{}, nil
+	}
+
+	result := &Attribution{LastCommit: commits[0].commit()}
+	for _, v := range commits {
+		var diffs []gitLabDiff
+		if _, err := c.get(ctx, fmt.Sprintf("projects/%v/repository/commits/%v/diff", project, v.ID), &diffs); err != nil {
+			return nil, err
+		}
+		for _, d := range diffs {
+			if d.NewPath == file.Path && addedIn(d.Diff, line) {
+				result.Introduced = v.commit()
+				return result, nil
+			}
+		}
+	}
+	return result, nil
+}
+
+func (v gitLabCommit) commit() *Commit {
+	return &Commit{
+		SHA:       v.ID,
+		URL:       v.WebURL,
+		Date:      v.CommittedDate,
+		Author:    identity(v.AuthorName, v.AuthorEmail),
+		Committer: identity(v.CommitterName, v.CommitterEmail),
+	}
+}
+
+func (c *gitLabCrawler) fetchProject(ctx context.Context, id string) (*gitLabProject, error) {
+	for _, p := range c.projects {
+		if strconv.Itoa(p.ID) == id || url.PathEscape(p.PathWithNamespace) == id {
+			return p, nil
+		}
+	}
+
+	var project gitLabProject
+	if _, err := c.get(ctx, "projects/"+id, &project); err != nil {
+		return nil, err
+	}
+	c.projects[project.ID] = &project
+	return &project, nil
+}
+
+func (c *gitLabCrawler) get(ctx context.Context, path string, v interface{}) (*http.Response, error) {
+	u, err := c.baseURL.Parse(path)
+	if err != nil {
+		return nil, err
+	}
+
+	wait := c.retryWait
+	for i := 0; ; i++ {
+		req, err := http.NewRequest(http.MethodGet, u.String(), nil)
+		if err != nil {
+			return nil, err
+		}
+		req = req.WithContext(ctx)
+		if c.token != "" {
+			req.Header.Set("PRIVATE-TOKEN", c.token)
+		}
+
+		resp, err := c.client.Do(req)
+		if err != nil {
+			return nil, err
+		}
+
+		if resp.StatusCode == http.StatusTooManyRequests {
+			resp.Body.Close()
+
+			if i >= c.maxRetries {
+				return nil, fmt.Errorf("GET %v: %v", u.String(), resp.Status)
+			}
+
+			// https://docs.gitlab.com/ee/user/admin_area/settings/user_and_ip_rate_limits.html#response-headers
+			d := wait
+			if retryAfter, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil && retryAfter > 0 {
+				d = time.Duration(retryAfter) * time.Second
+			}
+			log.Printf("retry after %v\n", d)
+
+			select {
+			case <-time.After(d):
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			}
+			wait *= 2
+			continue
+		}
+
+		err = func() error {
+			defer resp.Body.Close()
+			if resp.StatusCode < 200 || resp.StatusCode > 299 {
+				return fmt.Errorf("GET %v: %v", u.String(), resp.Status)
+			}
+			if w, ok := v.(io.Writer); ok {
+				_, err := io.Copy(w, resp.Body)
+				return err
+			}
+			return json.NewDecoder(resp.Body).Decode(v)
+		}()
+		return resp, err
+	}
+}
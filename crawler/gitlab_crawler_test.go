@@ -0,0 +1,163 @@
+/**
+ * Copyright (c) 2019-present Future Corporation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package crawler
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func newGitLabStub(t *testing.T) *httptest.Server {
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("/api/v4/search", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("PRIVATE-TOKEN") != "dummy-token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		if r.URL.Query().Get("scope") != "blobs" || r.URL.Query().Get("search") != "Copyright 2019 Example" {
+			t.Errorf("unexpected query: %v", r.URL.RawQuery)
+		}
+		switch r.URL.Query().Get("page") {
+		case "1":
+			w.Header().Set("X-Total", "3")
+			w.Header().Set("X-Next-Page", "2")
+			fmt.Fprint(w, `[
+				{"data": "Copyright 2019 Example", "path": "src/a.go", "ref": "master", "project_id": 2},
+				{"data": "// Copyright 2019 Example", "path": "src/b.go", "ref": "master", "project_id": 2}
+			]`)
+		case "2":
+			w.Header().Set("X-Next-Page", "")
+			fmt.Fprint(w, `[{"data": "Copyright 2019 Example", "path": "c.go", "ref": "dev", "project_id": 3}]`)
+		}
+	})
+	mux.HandleFunc("/api/v4/projects/", func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.EscapedPath() {
+		case "/api/v4/projects/1":
+			fmt.Fprint(w, `{"id": 1, "path": "origin", "path_with_namespace": "example/origin", "web_url": "https://gitlab.example.com/example/origin", "namespace": {"full_path": "example"}}`)
+		case "/api/v4/projects/2", "/api/v4/projects/ghost%2Fsub%2Fleak":
+			fmt.Fprint(w, `{"id": 2, "path": "leak", "path_with_namespace": "ghost/sub/leak", "web_url": "https://gitlab.example.com/ghost/sub/leak", "namespace": {"full_path": "ghost/sub"},
+				"created_at": "2019-07-01T09:00:00Z", "last_activity_at": "2019-08-01T09:00:00Z", "star_count": 5, "forks_count": 2, "default_branch": "master"}`)
+		case "/api/v4/projects/ghost%2Fsub%2Fleak/languages":
+			fmt.Fprint(w, `{"Go": 80.5, "Shell": 19.5}`)
+		case "/api/v4/projects/ghost%2Ffork/languages":
+			fmt.Fprint(w, `{}`)
+		case "/api/v4/projects/3", "/api/v4/projects/ghost%2Ffork":
+			fmt.Fprint(w, `{"id": 3, "path": "fork", "path_with_namespace": "ghost/fork", "web_url": "https://gitlab.example.com/ghost/fork", "namespace": {"full_path": "ghost"}, "forked_from_project": {"id": 4}}`)
+		case "/api/v4/projects/4":
+			fmt.Fprint(w, `{"id": 4, "path": "fork", "path_with_namespace": "someone/fork", "web_url": "https://gitlab.example.com/someone/fork", "namespace": {"full_path": "someone"}, "forked_from_project": {"id": 1}}`)
+		default:
+			t.Errorf("unexpected path: %v", r.URL.EscapedPath())
+			w.WriteHeader(http.StatusNotFound)
+		}
+	})
+	return httptest.NewServer(mux)
+}
+
+func TestGitLabSearch(t *testing.T) {
+	ts := newGitLabStub(t)
+	defer ts.Close()
+
+	c, err := NewGitLabCrawler(ts.URL+"/api/v4", "dummy-token")
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.(*gitLabCrawler).interval = 0
+
+	actual, coverage, err := c.Search(context.Background(), []string{"Copyright+2019+Example"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if coverage.Incomplete || coverage.Total != 3 || coverage.Fetched != 3 {
+		t.Errorf("got: %+v", coverage)
+	}
+
+	if len(actual) != 2 {
+		t.Fatalf("got: %v\nwant: %v", len(actual), 2)
+	}
+	if actual[0].Host != "gitlab.example.com" || actual[0].Owner != "ghost/sub" || actual[0].Name != "leak" || len(actual[0].HitFiles) != 2 {
+		t.Errorf("got: %+v", actual[0])
+	}
+	if actual[1].HitFiles[0].URL != "https://gitlab.example.com/ghost/fork/-/blob/dev/c.go" {
+		t.Errorf("got: %v", actual[1].HitFiles[0].URL)
+	}
+
+	fulfilled, err := c.Enrich(context.Background(), actual)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fulfilled[0].ForkSource != "" {
+		t.Errorf("got: %v\nwant: %v", fulfilled[0].ForkSource, "")
+	}
+	if fulfilled[0].Stars != 5 || fulfilled[0].Forks != 2 || fulfilled[0].Language != "Go" || fulfilled[0].DefaultBranch != "master" || fulfilled[0].PushedAt.IsZero() {
+		t.Errorf("got: %+v", fulfilled[0])
+	}
+	if fulfilled[1].ForkSource != "example/origin" {
+		t.Errorf("got: %v\nwant: %v", fulfilled[1].ForkSource, "example/origin")
+	}
+}
+
+func TestGitLabRateLimited(t *testing.T) {
+	var calls int
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		// no Retry-After
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer ts.Close()
+
+	c, err := NewGitLabCrawler(ts.URL+"/api/v4", "dummy-token")
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.(*gitLabCrawler).maxRetries = 2
+	c.(*gitLabCrawler).retryWait = time.Millisecond
+
+	if _, _, err := c.Search(context.Background(), []string{"Copyright"}); err == nil {
+		t.Errorf("got: nil\nwant: error")
+	}
+	if calls != 3 {
+		t.Errorf("got: %v\nwant: %v", calls, 3)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	c.(*gitLabCrawler).retryWait = time.Hour
+	if _, _, err := c.Search(ctx, []string{"Copyright"}); err != context.DeadlineExceeded {
+		t.Errorf("got: %v\nwant: %v", err, context.DeadlineExceeded)
+	}
+}
+
+func TestGitLabSearchCanceledBetweenPages(t *testing.T) {
+	ts := newGitLabStub(t)
+	defer ts.Close()
+
+	c, err := NewGitLabCrawler(ts.URL+"/api/v4", "dummy-token")
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.(*gitLabCrawler).interval = time.Hour
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if _, _, err := c.Search(ctx, []string{"Copyright+2019+Example"}); err != context.DeadlineExceeded {
+		t.Errorf("got: %v\nwant: %v", err, context.DeadlineExceeded)
+	}
+}
@@ -0,0 +1,420 @@
+/**
+ * Copyright (c) 2019-present Future Corporation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package crawler
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+const (
+	// LocalHost labels the results of local scans.
+	LocalHost = "local"
+
+	// maxLocalFileSize is the byte size limit of files searched in a working tree.
+	maxLocalFileSize = 1024 * 1024
+
+	// binaryCheckSize is the byte size checked for NUL to detect binary files, same as git.
+	binaryCheckSize = 8000
+)
+
+// localCrawler searches a working tree or the history of a local git repository instead of the search API,
+// so that a leak can be found before it is pushed.
+type localCrawler struct {
+	dir     string
+	history bool
+	revs    []string
+}
+
+// NewLocalCrawler returns the crawler for the directory. If history is true, the lines added in `git log -p revs` are searched
+// instead of the working tree. Empty revs means all refs.
+func NewLocalCrawler(dir string, history bool, revs []string) (Crawler, error) {
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, err
+	}
+	return &localCrawler{
+		dir:     abs,
+		history: history,
+		revs:    revs,
+	}, nil
+}
+
+// Search returns the lines that contain any word of the queries with a line before and after them as fragments,
+// like the text matches of the search API. The queries are evaluated exactly by the skip filter.
+func (c *localCrawler) Search(ctx context.Context, words []string) (Repositories, Coverage, error) {
+	tokens := queryTokens(words)
+
+	var files Files
+	var err error
+	if c.history {
+		files, err = c.searchHistory(ctx, tokens)
+	} else {
+		files, err = c.searchTree(ctx, tokens)
+	}
+	if err != nil {
+		return nil, Coverage{}, err
+	}
+
+	coverage := Coverage{Total: len(files), Fetched: len(files)}
+	if len(files) == 0 {
+		return nil, coverage, nil
+	}
+	return Repositories{{
+		URL:      "file://" + filepath.ToSlash(c.dir),
+		Host:     LocalHost,
+		Owner:    filepath.Base(filepath.Dir(c.dir)), // parent directory, to label like owner/name
+		Name:     filepath.Base(c.dir),
+		HitFiles: files,
+	}}, coverage, nil
+}
+
+// Enrich does nothing. A local repository is not a fork and not published.
+func (c *localCrawler) Enrich(ctx context.Context, repos Repositories) (Repositories, error) {
+	return repos, nil
+}
+
+// FetchContent reads the file of the working tree, or `git show <commit>:<path>` in the history mode.
+func (c *localCrawler) FetchContent(ctx context.Context, repo Repository, file File, maxSize int) ([]byte, error) {
+	w := &limitWriter{max: maxSize}
+	if c.history {
+		cmd := exec.CommandContext(ctx, "git", "-C", c.dir, "show", file.URL)
+		cmd.Stdout = w
+		if err := cmd.Run(); err != nil {
+			if w.exceeded {
+				return nil, ErrTooLarge
+			}
+			return nil, err
+		}
+		return w.buf, nil
+	}
+
+	f, err := os.Open(filepath.Join(c.dir, filepath.FromSlash(file.URL)))
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	if _, err := io.Copy(w, f); err != nil {
+		if w.exceeded {
+			return nil, ErrTooLarge
+		}
+		return nil, err
+	}
+	return w.buf, nil
+}
+
+// gitLogFormat is the format of gitCommit. Fields are separated by NUL.
+const gitLogFormat = "--format=%H%x00%cI%x00%an%x00%ae%x00%cn%x00%ce"
+
+// Attribute looks up the commits of the file with git log. The history mode starts from the commit of the hit.
+func (c *localCrawler) Attribute(ctx context.Context, repo Repository, file File, line string) (*Attribution, error) {
+	rev, path := "HEAD", file.URL
+	if c.history {
+		i := strings.Index(file.URL, ":")
+		if i < 0 {
+			return nil, fmt.Errorf("unknown commit: %v", file.URL)
+		}
+		rev, path = file.URL[:i], file.URL[i+1:]
+	}
+
+	last, err := c.gitCommit(ctx, rev, "--", path)
+	if err != nil {
+		return nil, err
+	}
+	result := &Attribution{LastCommit: last}
+	if line != "" {
+		// pickaxe finds the commits that change the number of occurrences
+		result.Introduced, err = c.gitCommit(ctx, "-S"+line, rev, "--", path)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
+}
+
+// gitCommit returns the first commit of git log. It returns nil if no commit is found, e.g. an untracked file.
+func (c *localCrawler) gitCommit(ctx context.Context, args ...string) (*Commit, error) {
+	out, err := exec.CommandContext(ctx, "git", append([]string{"-C", c.dir, "log", "-1", gitLogFormat}, args...)...).Output()
+	if err != nil {
+		return nil, err
+	}
+	fields := strings.Split(strings.TrimSpace(string(out)), "\x00")
+	if len(fields) != 6 {
+		return nil, nil
+	}
+	date, err := time.Parse(time.RFC3339, fields[1])
+	if err != nil {
+		return nil, err
+	}
+	return &Commit{
+		SHA:       fields[0],
+		Date:      date,
+		Author:    identity(fields[2], fields[3]),
+		Committer: identity(fields[4], fields[5]),
+	}, nil
+}
+
+func (c *localCrawler) searchTree(ctx context.Context, tokens []string) (Files, error) {
+	paths, err := c.listFiles(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var result Files
+	for _, path := range paths {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		fragments, err := searchFile(filepath.Join(c.dir, filepath.FromSlash(path)), tokens)
+		if err != nil {
+			return nil, err
+		}
+		if len(fragments) > 0 {
+			result = append(result, File{URL: path, Path: path, Fragments: fragments})
+		}
+	}
+	return result, nil
+}
+
+// listFiles returns the slash separated relative paths of the files. Files ignored by git are excluded in a git repository.
+func (c *localCrawler) listFiles(ctx context.Context) ([]string, error) {
+	out, err := exec.CommandContext(ctx, "git", "-C", c.dir, "ls-files", "-z", "--cached", "--others", "--exclude-standard").Output()
+	if err == nil {
+		var result []string
+		for _, v := range strings.Split(string(out), "\x00") {
+			if v != "" {
+				result = append(result, v)
+			}
+		}
+		return result, nil
+	}
+
+	// not a git repository
+	var result []string
+	err = filepath.Walk(c.dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			if info.Name() == ".git" {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		rel, err := filepath.Rel(c.dir, path)
+		if err != nil {
+			return err
+		}
+		result = append(result, filepath.ToSlash(rel))
+		return nil
+	})
+	return result, err
+}
+
+func searchFile(path string, tokens []string) ([]string, error) {
+	info, err := os.Lstat(path)
+	if os.IsNotExist(err) {
+		// deleted but not staged
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !info.Mode().IsRegular() || info.Size() > maxLocalFileSize {
+		return nil, nil
+	}
+
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	head := b
+	if len(head) > binaryCheckSize {
+		head = head[:binaryCheckSize]
+	}
+	if bytes.IndexByte(head, 0) >= 0 {
+		return nil, nil
+	}
+	return fragmentsOf(strings.Split(string(b), "\n"), tokens), nil
+}
+
+func (c *localCrawler) searchHistory(ctx context.Context, tokens []string) (Files, error) {
+	args := []string{"-C", c.dir, "log", "-p", "--no-color", "--no-ext-diff", "--format=commit %H"}
+	if len(c.revs) == 0 {
+		args = append(args, "--all")
+	}
+	args = append(args, c.revs...)
+
+	cmd := exec.CommandContext(ctx, "git", args...)
+	cmd.Stderr = os.Stderr
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+
+	result, err := parseLog(stdout, tokens)
+	if err != nil {
+		cmd.Process.Kill()
+		cmd.Wait()
+		return nil, err
+	}
+	if err := cmd.Wait(); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// parseLog searches the lines added in the output of `git log -p --format="commit %H"`.
+// The URL of a file is "<commit>:<path>", which can be shown by `git show`.
+func parseLog(r io.Reader, tokens []string) (Files, error) {
+	var (
+		result  Files
+		commit  string
+		path    string
+		added   []string
+		header  bool // between "diff --git" and the first hunk
+		oldPath bool // the previous line is the "--- " header
+	)
+	flush := func() {
+		if path != "" && len(added) > 0 {
+			if fragments := fragmentsOf(added, tokens); len(fragments) > 0 {
+				result = result.Merge(File{URL: commit + ":" + path, Path: path, Fragments: fragments})
+			}
+		}
+		added = nil
+	}
+
+	br := bufio.NewReader(r)
+	for {
+		line, err := br.ReadString('\n')
+		if err != nil && err != io.EOF {
+			return nil, err
+		}
+		line = strings.TrimSuffix(line, "\n")
+
+		// "--- " and "+++ " are file headers only before the first hunk, otherwise an added line such as "++ x" is taken as a header
+		afterOldPath := oldPath
+		oldPath = false
+		switch {
+		case strings.HasPrefix(line, "commit "):
+			flush()
+			commit, path, header = strings.TrimPrefix(line, "commit "), "", false
+		case strings.HasPrefix(line, "diff --git "):
+			flush()
+			path, header = "", true
+		case header && strings.HasPrefix(line, "--- "):
+			oldPath = true
+		case header && afterOldPath && strings.HasPrefix(line, "+++ "):
+			path = diffPath(strings.TrimPrefix(line, "+++ "))
+		case header:
+			// mode and index lines. the first hunk ends the header
+			header = !strings.HasPrefix(line, "@@")
+		case strings.HasPrefix(line, "+"):
+			added = append(added, line[1:])
+		default:
+			// context, removed lines and hunk headers separate the added lines
+			flush()
+		}
+
+		if err == io.EOF {
+			flush()
+			return result, nil
+		}
+	}
+}
+
+// diffPath returns the path of "b/path". It returns empty string for a deleted file.
+func diffPath(s string) string {
+	s = strings.Trim(s, `"`)
+	if s == "/dev/null" {
+		return ""
+	}
+	return strings.TrimPrefix(s, "b/")
+}
+
+// fragmentsOf returns the lines that contain any token with a line before and after them. Overlapped fragments are merged.
+func fragmentsOf(lines []string, tokens []string) []string {
+	var result []string
+	end := -1 // end of the last fragment
+	start := -1
+	for i, line := range lines {
+		if !containsAnyFold(line, tokens) {
+			continue
+		}
+		from, to := i-1, i+2
+		if from < 0 {
+			from = 0
+		}
+		if to > len(lines) {
+			to = len(lines)
+		}
+		if start >= 0 && from <= end {
+			end = to
+			continue
+		}
+		if start >= 0 {
+			result = append(result, strings.Join(lines[start:end], "\n"))
+		}
+		start, end = from, to
+	}
+	if start >= 0 {
+		result = append(result, strings.Join(lines[start:end], "\n"))
+	}
+	return result
+}
+
+func containsAnyFold(line string, tokens []string) bool {
+	lower := strings.ToLower(line)
+	for _, v := range tokens {
+		if strings.Contains(lower, v) {
+			return true
+		}
+	}
+	return false
+}
+
+// queryTokens returns the lower case words of the search queries. Operators, negated words and qualifiers such as "extension:go" are excluded.
+func queryTokens(words []string) []string {
+	var result []string
+	for _, w := range words {
+		negative := false
+		for _, v := range strings.Fields(strings.Replace(w, "+", " ", -1)) {
+			if v == "NOT" {
+				negative = true
+				continue
+			}
+			v = strings.Trim(v, `"()`)
+			if negative || v == "" || v == "AND" || v == "OR" || strings.Contains(v, ":") {
+				negative = false
+				continue
+			}
+			result = append(result, strings.ToLower(v))
+		}
+	}
+	return result
+}
@@ -0,0 +1,134 @@
+/**
+ * Copyright (c) 2019-present Future Corporation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package crawler
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const gitLog = `commit 2c9f1b4
+diff --git a/LICENSE b/LICENSE
+new file mode 100644
+--- /dev/null
++++ b/LICENSE
+@@ -0,0 +1,3 @@
++MIT License
++Copyright 2019 Future Corporation
++Permission is hereby granted
+commit 8e1d2a7
+diff --git a/main.go b/main.go
+--- a/main.go
++++ b/main.go
+@@ -1,3 +1,3 @@
+ package main
+-// Copyright 2019 Future Corporation
++// Copyright 2019 Example
+diff --git a/old.go b/old.go
+deleted file mode 100644
+--- a/old.go
++++ /dev/null
+@@ -1 +0,0 @@
+-// Copyright 2019 Future Corporation
+commit 5b7c3e0
+diff --git a/README.md b/README.md
+--- a/README.md
++++ b/README.md
+@@ -1,2 +1,4 @@
+ # sample
+--- Copyright 2019 Future Corporation
++++ Copyright 2019 Future Corporation
++Copyright 2019 Future Corporation
+`
+
+func TestParseLog(t *testing.T) {
+	files, err := parseLog(strings.NewReader(gitLog), queryTokens([]string{"Copyright+Future"}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := Files{
+		{URL: "2c9f1b4:LICENSE", Path: "LICENSE", Fragments: []string{"MIT License\nCopyright 2019 Future Corporation\nPermission is hereby granted"}},
+		{URL: "8e1d2a7:main.go", Path: "main.go", Fragments: []string{"// Copyright 2019 Example"}},
+		// the added line "++ Copyright ..." is not a file header
+		{URL: "5b7c3e0:README.md", Path: "README.md", Fragments: []string{"++ Copyright 2019 Future Corporation\nCopyright 2019 Future Corporation"}},
+	}
+	if !reflect.DeepEqual(files, expected) {
+		t.Errorf("got: %+v\nwant: %+v", files, expected)
+	}
+}
+
+func TestLocalCrawlerTree(t *testing.T) {
+	dir, err := ioutil.TempDir("", "codediaper")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	write := func(name, content string) {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("src/a.go", "package a\n\n// COPYRIGHT 2019 Future Corporation\nfunc A() {}\n")
+	write("b.go", "package b\n")
+	write("c.bin", "Copyright\x00")
+	write(".git/config", "Copyright")
+
+	c, err := NewLocalCrawler(dir, false, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	repos, coverage, err := c.Search(context.Background(), []string{"Copyright+2019"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(repos) != 1 || repos[0].Host != LocalHost || repos[0].Name != filepath.Base(dir) {
+		t.Fatalf("got: %+v", repos)
+	}
+	expected := Files{{URL: "src/a.go", Path: "src/a.go", Fragments: []string{"\n// COPYRIGHT 2019 Future Corporation\nfunc A() {}"}}}
+	if !reflect.DeepEqual(repos[0].HitFiles, expected) {
+		t.Errorf("got: %+v\nwant: %+v", repos[0].HitFiles, expected)
+	}
+	if coverage.Total != 1 || coverage.Incomplete {
+		t.Errorf("got: %+v", coverage)
+	}
+}
+
+func TestFragmentsOf(t *testing.T) {
+	lines := []string{"a", "key 1", "b", "key 2", "c", "d", "e", "key 3"}
+	expected := []string{"a\nkey 1\nb\nkey 2\nc", "e\nkey 3"}
+	if actual := fragmentsOf(lines, []string{"key"}); !reflect.DeepEqual(actual, expected) {
+		t.Errorf("got: %q\nwant: %q", actual, expected)
+	}
+}
+
+func TestQueryTokens(t *testing.T) {
+	expected := []string{"copyright", "future", "inc", "apache"}
+	if actual := queryTokens([]string{`Copyright+Future extension:go`, `(Inc OR "Apache") NOT License`}); !reflect.DeepEqual(actual, expected) {
+		t.Errorf("got: %v\nwant: %v", actual, expected)
+	}
+}
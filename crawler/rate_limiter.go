@@ -0,0 +1,107 @@
+/**
+ * Copyright (c) 2019-present Future Corporation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package crawler
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// RateLimiter is a token bucket shared by the goroutines that consume the same API budget.
+// The bucket is corrected by the rate limit headers of responses, so that it never exceeds the budget
+// even if another process uses the same token.
+type RateLimiter struct {
+	mu      sync.Mutex
+	rate    float64 // tokens per second
+	burst   float64
+	tokens  float64
+	last    time.Time
+	resetAt time.Time // no request is sent until this time because the budget is exhausted
+}
+
+// NewRateLimiter returns a limiter that allows limit requests per period. The bucket is full at first.
+func NewRateLimiter(limit int, per time.Duration) *RateLimiter {
+	return &RateLimiter{
+		rate:   float64(limit) / per.Seconds(),
+		burst:  float64(limit),
+		tokens: float64(limit),
+		last:   time.Now(),
+	}
+}
+
+// Wait blocks until a request is allowed or ctx is done.
+func (l *RateLimiter) Wait(ctx context.Context) error {
+	for {
+		l.mu.Lock()
+		now := time.Now()
+
+		var wait time.Duration
+		if now.Before(l.resetAt) {
+			wait = l.resetAt.Sub(now)
+		} else {
+			l.refill(now)
+			if l.tokens >= 1 {
+				l.tokens--
+				l.mu.Unlock()
+				return nil
+			}
+			wait = time.Duration((1 - l.tokens) / l.rate * float64(time.Second))
+		}
+		l.mu.Unlock()
+
+		select {
+		case <-time.After(wait):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// Update corrects the bucket by the remaining budget and its reset time reported by the server.
+// e.g. X-RateLimit-Remaining and X-RateLimit-Reset headers of GitHub.
+func (l *RateLimiter) Update(remaining int, reset time.Time) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.refill(time.Now())
+	if remaining <= 0 {
+		l.tokens = 0
+		l.resetAt = reset
+		return
+	}
+	if float64(remaining) < l.tokens {
+		l.tokens = float64(remaining)
+	}
+}
+
+// Pause stops all requests for d. It is used for the Retry-After header.
+func (l *RateLimiter) Pause(d time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if resetAt := time.Now().Add(d); resetAt.After(l.resetAt) {
+		l.resetAt = resetAt
+	}
+}
+
+func (l *RateLimiter) refill(now time.Time) {
+	l.tokens += now.Sub(l.last).Seconds() * l.rate
+	if l.tokens > l.burst {
+		l.tokens = l.burst
+	}
+	l.last = now
+}
@@ -0,0 +1,58 @@
+/**
+ * Copyright (c) 2019-present Future Corporation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package crawler
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestRateLimiterBurst(t *testing.T) {
+	l := NewRateLimiter(2, 200*time.Millisecond)
+
+	start := time.Now()
+	for i := 0; i < 3; i++ {
+		if err := l.Wait(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// two tokens are in the bucket at first, the third waits for refill(100ms)
+	if elapsed := time.Since(start); elapsed < 80*time.Millisecond {
+		t.Errorf("got: %v\nwant: more than 80ms", elapsed)
+	}
+}
+
+func TestRateLimiterUpdate(t *testing.T) {
+	l := NewRateLimiter(1000, time.Second)
+	l.Update(0, time.Now().Add(100*time.Millisecond))
+
+	start := time.Now()
+	if err := l.Wait(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := time.Since(start); elapsed < 80*time.Millisecond {
+		t.Errorf("got: %v\nwant: more than 80ms", elapsed)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	l.Pause(time.Hour)
+	if err := l.Wait(ctx); err != context.Canceled {
+		t.Errorf("got: %v\nwant: %v", err, context.Canceled)
+	}
+}
@@ -15,21 +15,56 @@
  */
 package crawler
 
+import (
+	"net/url"
+	"time"
+)
+
 type Repository struct {
-	URL        string
-	Owner      string
-	Name       string
-	HitFiles   Files
-	ForkSource string // parent is the repository this repository was forked from, source is the ultimate source for the network. https://developer.github.com/v3/repos/#response-4
+	URL        string `json:"url"`
+	Host       string `json:"host"` // e.g. github.com, gitlab.com or the host name of the self-hosted service
+	Owner      string `json:"owner"`
+	Name       string `json:"name"`
+	HitFiles   Files  `json:"files"`
+	ForkSource string `json:"fork_source,omitempty"` // parent is the repository this repository was forked from, source is the ultimate source for the network. https://developer.github.com/v3/repos/#response-4
+
+	// metadata to estimate how exposed the repository is. They are filled by Crawler.Enrich.
+	CreatedAt     time.Time `json:"created_at"`
+	PushedAt      time.Time `json:"pushed_at"` // last activity of GitLab
+	Stars         int       `json:"stars"`
+	Forks         int       `json:"forks"`
+	DefaultBranch string    `json:"default_branch,omitempty"`
+	Archived      bool      `json:"archived,omitempty"`
+	Language      string    `json:"language,omitempty"` // primary language
 }
 
 type Repositories []Repository
 
+// Coverage reports how much of the hits the search API claimed were actually fetched.
+type Coverage struct {
+	Total      int  `json:"total"`
+	Fetched    int  `json:"fetched"`
+	Incomplete bool `json:"incomplete"` // true if some hits could not be fetched because of the result cap, rate limit or timeout
+}
+
 // Fragments is represents github api result
 // https://developer.github.com/v3/search/#text-match-metadata
 type File struct {
-	URL       string
-	Fragments []string
+	URL         string       `json:"url"`
+	Fragments   []string     `json:"fragments"`
+	Path        string       `json:"path,omitempty"`        // path in the repository
+	Ref         string       `json:"ref,omitempty"`         // blob SHA of GitHub, or branch of GitLab
+	Verified    bool         `json:"verified,omitempty"`    // true if the whole content is matched instead of the fragments
+	Lines       []int        `json:"lines,omitempty"`       // 1-based line numbers matched in the whole content
+	Similar     []Similarity `json:"similar,omitempty"`     // internal files copied to this file
+	Attribution *Attribution `json:"attribution,omitempty"` // who pushed the file and when
+}
+
+// Similarity is an internal file found in the fingerprint index.
+type Similarity struct {
+	Path  string  `json:"path"`
+	Score float64 `json:"score"` // 0 to 1
+	Lines []int   `json:"lines"` // lines of the hit file that share fingerprints
 }
 
 type Files []File
@@ -98,3 +133,12 @@ func Contains(arr []int, e int) bool {
 	}
 	return false
 }
+
+// HostOf returns the host name of the URL. If the URL is invalid, it returns empty string.
+func HostOf(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return ""
+	}
+	return u.Host
+}
@@ -0,0 +1,80 @@
+/**
+ * Copyright (c) 2019-present Future Corporation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package diaper
+
+import (
+	"context"
+	"github.com/future-architect/code-diaper/crawler"
+	"log"
+	"regexp"
+	"strings"
+)
+
+// attribute records the commits of the hit files. A file whose commits can not be looked up is left as it is.
+func attribute(ctx context.Context, attributor crawler.Attributor, repos crawler.Repositories, keywords, patterns []string) crawler.Repositories {
+	var res []*regexp.Regexp
+	for _, v := range patterns {
+		if re, err := regexp.Compile(v); err == nil {
+			res = append(res, re)
+		}
+	}
+
+	result := make(crawler.Repositories, 0, len(repos))
+	for _, r := range repos {
+		files := make(crawler.Files, 0, len(r.HitFiles))
+		for _, file := range r.HitFiles {
+			attribution, err := attributor.Attribute(ctx, r, file, matchedLine(file, keywords, res))
+			if err != nil {
+				log.Printf("commits are not found %v: %v\n", file.URL, err)
+			} else {
+				file.Attribution = attribution
+			}
+			files = append(files, file)
+		}
+		r.HitFiles = files
+		result = append(result, r)
+	}
+	return result
+}
+
+// matchedLine returns the first line of the fragments that contains a keyword or matches a pattern, to find the commit that introduced it.
+// If no line matches, e.g. the match of the pattern spans lines, the first non-empty line is returned.
+func matchedLine(file crawler.File, keywords []string, patterns []*regexp.Regexp) string {
+	var first string
+	for _, fragment := range file.Fragments {
+		for _, line := range strings.Split(fragment, "\n") {
+			line = strings.TrimSpace(line)
+			if line == "" {
+				continue
+			}
+			if first == "" {
+				first = line
+			}
+			for _, re := range patterns {
+				if re.MatchString(line) {
+					return line
+				}
+			}
+			lower := strings.ToLower(line)
+			for _, k := range keywords {
+				if k != "" && strings.Contains(lower, strings.ToLower(k)) {
+					return line
+				}
+			}
+		}
+	}
+	return first
+}
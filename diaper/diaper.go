@@ -18,84 +18,301 @@ package diaper
 import (
 	"context"
 	"errors"
+	"fmt"
 	"github.com/future-architect/code-diaper/condition"
 	"github.com/future-architect/code-diaper/crawler"
 	"github.com/future-architect/code-diaper/filter"
 	"github.com/future-architect/code-diaper/formatter"
+	"github.com/future-architect/code-diaper/store"
 	"strings"
+	"sync"
+	"time"
 )
 
-func Run(ctx context.Context, ops condition.Options) (*Message, error) {
+// Run executes the searches and returns the structured result. Use NewMessage or formatter.Write to render it.
+func Run(ctx context.Context, ops condition.Options) (*formatter.ScanResult, error) {
 
+	if len(ops.SearchList) == 0 {
+		return nil, errors.New("required parameter: SearchList must be at least one")
+	}
+
+	startedAt := time.Now()
 	searchList := ops.ExpandSearch()
 
-	var resultList []formatter.SearchResult
-	for _, search := range searchList {
-		detect, err := RunSearch(ctx, ops.GitHubToken, search)
+	resultList, err := runSearchList(ctx, ops, searchList)
+	if err != nil {
+		return nil, err
+	}
+
+	if ops.StorePath != "" {
+		st, err := store.NewStore(ctx, ops.StorePath)
+		if err != nil {
+			return nil, err
+		}
+		resultList, err = reconcile(ctx, st, resultList, ops.ReportAll, time.Now())
 		if err != nil {
 			return nil, err
 		}
-		resultList = append(resultList, formatter.NewSearchResult(strings.Join(search.StringWordList(), "&"), detect))
 	}
 
-	if len(resultList) == 0 {
-		msg := ""
-		for _, v := range searchList {
-			if msg != "" {
-				msg += ","
+	return &formatter.ScanResult{
+		StartedAt:  startedAt,
+		FinishedAt: time.Now(),
+		Searches:   resultList,
+	}, nil
+}
+
+// reconcile classifies the hits into new, still-present and resolved by comparing with the stored findings.
+// Unless reportAll is true, results are narrowed down to new findings.
+// Findings triaged as false positive or acknowledged are not reported.
+func reconcile(ctx context.Context, st store.Store, resultList []formatter.SearchResult, reportAll bool, now time.Time) ([]formatter.SearchResult, error) {
+	stored, err := st.Load(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var current store.Findings
+	for _, sr := range resultList {
+		for _, f := range store.NewFindings(sr.Query, sr.Repos, now) {
+			current = current.Merge(f)
+		}
+	}
+
+	searched := func(f store.Finding) bool {
+		for _, sr := range resultList {
+			if f.HasQuery(sr.Query) && sr.Host == crawler.HostOf(f.RepositoryURL) && !sr.Coverage.Incomplete {
+				return true
 			}
-			msg += "「" + strings.Join(v.StringWordList(), "&") + "」"
 		}
-		return &Message{
-			Summary: "GitHub Search Result is 0. Query:" + msg,
-			Details: nil,
-		}, nil
+		return false
 	}
 
-	summary, err := formatter.FmtTop(resultList)
-	if err != nil {
+	diff := store.Reconcile(stored, current, searched, now)
+	if err := st.Save(ctx, diff.All); err != nil {
 		return nil, err
 	}
 
-	var details []string
-	for _, v := range resultList {
-		if len(v.Repos) == 0 {
-			continue
+	triageFilter := filter.NewTriageFilter(diff.All)
+	resolvedRepos := diff.All.ResolvedRepositories()
+
+	result := make([]formatter.SearchResult, 0, len(resultList))
+	for _, sr := range resultList {
+		if !reportAll {
+			sr.Repos = onlyFindings(sr.Repos, diff.New)
 		}
-		detail, err := formatter.FmtDetail(v)
-		if err != nil {
-			return nil, err
+		sr.Repos = triageFilter.Do(sr.Repos)
+		sr.HitCount = len(sr.Repos)
+		sr.New = diff.New.Query(sr.Query).Unsuppressed().IDs()
+		sr.Resolved = diff.Resolved.Query(sr.Query).Unsuppressed()
+		sr.ResolvedRepos = nil
+		for _, f := range diff.Resolved.Query(sr.Query) {
+			if contains(resolvedRepos, f.RepositoryURL) && !contains(sr.ResolvedRepos, f.RepositoryURL) {
+				sr.ResolvedRepos = append(sr.ResolvedRepos, f.RepositoryURL)
+			}
 		}
-		details = append(details, detail)
+		result = append(result, sr)
 	}
+	return result, nil
+}
 
-	return &Message{
-		Summary: summary,
-		Details: details,
-	}, nil
+// onlyFindings leaves the fragments contained in findings.
+func onlyFindings(repos crawler.Repositories, findings store.Findings) crawler.Repositories {
+	var result crawler.Repositories
+	for _, r := range repos {
+		var files crawler.Files
+		for _, f := range r.HitFiles {
+			var fragments []string
+			for _, fragment := range f.Fragments {
+				if findings.Contains(r.URL, f, fragment) {
+					fragments = append(fragments, fragment)
+				}
+			}
+			if len(fragments) > 0 {
+				f.Fragments = fragments
+				files = append(files, f)
+			}
+		}
+		if len(files) > 0 {
+			r.HitFiles = files
+			result = append(result, r)
+		}
+	}
+	return result
 }
 
-func RunSearch(ctx context.Context, githubToken string, s condition.Search) (crawler.Repositories, error) {
+// runSearchList executes searches concurrently. API calls are throttled by the rate limiter shared by the crawlers.
+// The order of the results is the same as searchList.
+func runSearchList(ctx context.Context, ops condition.Options, searchList []condition.Search) ([]formatter.SearchResult, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	resultList := make([]formatter.SearchResult, len(searchList))
+	errs := make([]error, len(searchList))
+
+	sem := make(chan struct{}, ops.ConcurrencyOrDefault())
+	var wg sync.WaitGroup
+	for i, search := range searchList {
+		wg.Add(1)
+		go func(i int, search condition.Search) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
 
-	if githubToken == "" {
-		return nil, errors.New("required parameter: GitHubToken")
+			detect, coverage, err := RunSearch(ctx, ops, search)
+			if err != nil {
+				errs[i] = err
+				cancel() // stop the other searches
+				return
+			}
+			resultList[i] = formatter.NewSearchResult(Host(ops, search), strings.Join(search.StringWordList(), "&"), detect, coverage)
+			resultList[i].Keywords = search.Keywords()
+			resultList[i].Patterns = search.Patterns()
+			resultList[i].MailTo = search.MailTo
+		}(i, search)
+	}
+	wg.Wait()
+
+	// the searches stopped by another failure return context.Canceled, so report the original error first
+	for _, err := range errs {
+		if err != nil && err != context.Canceled {
+			return nil, err
+		}
 	}
+	for _, err := range errs {
+		if err != nil {
+			return nil, err
+		}
+	}
+	return resultList, nil
+}
+
+func RunSearch(ctx context.Context, ops condition.Options, s condition.Search) (crawler.Repositories, crawler.Coverage, error) {
+
 	if len(s.QueryList) == 0 {
-		return nil, errors.New("required parameter: SearchWord must be at least one")
+		return nil, crawler.Coverage{}, errors.New("required parameter: SearchWord must be at least one")
+	}
+	for _, v := range s.QueryList {
+		if err := v.Validate(); err != nil {
+			return nil, crawler.Coverage{}, err
+		}
+	}
+
+	gc, err := newCrawler(ops, s)
+	if err != nil {
+		return nil, crawler.Coverage{}, err
+	}
+	return search(ctx, ops, gc, s)
+}
+
+// RunLocal executes the searches with the crawler of a local repository. The findings are not stored.
+func RunLocal(ctx context.Context, ops condition.Options, gc crawler.Crawler) (*formatter.ScanResult, error) {
+	if len(ops.SearchList) == 0 {
+		return nil, errors.New("required parameter: SearchList must be at least one")
+	}
+
+	startedAt := time.Now()
+	var resultList []formatter.SearchResult
+	for _, s := range ops.ExpandSearch() {
+		for _, v := range s.QueryList {
+			if err := v.Validate(); err != nil {
+				return nil, err
+			}
+		}
+		detect, coverage, err := search(ctx, ops, gc, s)
+		if err != nil {
+			return nil, err
+		}
+		sr := formatter.NewSearchResult(crawler.LocalHost, strings.Join(s.StringWordList(), "&"), detect, coverage)
+		sr.Keywords = s.Keywords()
+		sr.Patterns = s.Patterns()
+		resultList = append(resultList, sr)
 	}
 
-	repos := strings.Split(s.SkipRepos, ",")
-	libs := strings.Split(s.SkipLibs, ",")
-	owners := strings.Split(s.SkipOwners, ",")
-	skipFilter := filter.NewSkipFilter(s.QueryList, repos, libs, owners)
+	return &formatter.ScanResult{
+		StartedAt:  startedAt,
+		FinishedAt: time.Now(),
+		Searches:   resultList,
+	}, nil
+}
 
-	gc := crawler.NewGitHubCrawler(githubToken)
+func search(ctx context.Context, ops condition.Options, gc crawler.Crawler, s condition.Search) (crawler.Repositories, crawler.Coverage, error) {
+	skipFilter := filter.NewSkipFilter(s.QueryList, s.SkipRepos, s.SkipLibs, s.SkipOwners)
 
-	originalResult, err := gc.Search(ctx, s.StringWordList())
+	originalResult, coverage, err := gc.Search(ctx, s.QueryWordList())
 	if err != nil {
-		return nil, err
+		return nil, coverage, err
+	}
+
+	if fetcher, ok := gc.(crawler.ContentFetcher); ok && ops.Verify {
+		// skipped files are not downloaded
+		pathFilter := filter.NewSkipFilter(nil, s.SkipRepos, s.SkipLibs, s.SkipOwners)
+		originalResult = filter.NewVerifier(s.QueryList, fetcher, ops.VerifyMaxSizeOrDefault()).Do(ctx, pathFilter.Do(originalResult))
+	}
+
+	filtered := skipFilter.Do(originalResult)
+	if fetcher, ok := gc.(crawler.ContentFetcher); ok && ops.FingerprintIndex != "" {
+		idx, err := loadIndex(ops.FingerprintIndex)
+		if err != nil {
+			return nil, coverage, err
+		}
+		filtered = annotateSimilar(ctx, idx, fetcher, filtered, ops.VerifyMaxSizeOrDefault(), ops.FingerprintThresholdOrDefault())
+	}
+
+	if attributor, ok := gc.(crawler.Attributor); ok && ops.Attribution {
+		filtered = attribute(ctx, attributor, filtered, s.Keywords(), s.Patterns())
 	}
 
 	// if repository that has skip name is forked and renamed then it is too skipped.
-	return gc.FulfillForkSource(ctx, skipFilter.Do(originalResult))
+	result, err := gc.Enrich(ctx, filtered)
+	return result, coverage, err
+}
+
+// newCrawler is replaced in tests.
+var newCrawler = NewCrawler
+
+// NewCrawler returns the crawler for the provider of the search.
+func NewCrawler(ops condition.Options, s condition.Search) (crawler.Crawler, error) {
+	switch s.ProviderName() {
+	case condition.ProviderGitHub:
+		token, baseURL, uploadURL := ops.GitHubEndpoint(s)
+		if token == "" {
+			return nil, errors.New("required parameter: GitHubToken")
+		}
+		if baseURL != "" {
+			return crawler.NewGitHubEnterpriseCrawler(token, baseURL, uploadURL)
+		}
+		return crawler.NewGitHubCrawler(token), nil
+	case condition.ProviderGitLab:
+		if ops.GitLabToken == "" {
+			return nil, errors.New("required parameter: GitLabToken")
+		}
+		return crawler.NewGitLabCrawler(ops.GitLabBaseURL, ops.GitLabToken)
+	default:
+		return nil, fmt.Errorf("unknown provider: %v", s.Provider)
+	}
+}
+
+// Host returns the host name of the code hosting service of the search for labeling results.
+func Host(ops condition.Options, s condition.Search) string {
+	switch s.ProviderName() {
+	case condition.ProviderGitLab:
+		if ops.GitLabBaseURL != "" {
+			return crawler.HostOf(ops.GitLabBaseURL)
+		}
+		return "gitlab.com"
+	default:
+		if _, baseURL, _ := ops.GitHubEndpoint(s); baseURL != "" {
+			return crawler.HostOf(baseURL)
+		}
+		return "github.com"
+	}
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
 }
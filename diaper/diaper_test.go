@@ -0,0 +1,219 @@
+/**
+ * Copyright (c) 2019-present Future Corporation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package diaper
+
+import (
+	"context"
+	"errors"
+	"github.com/future-architect/code-diaper/condition"
+	"github.com/future-architect/code-diaper/crawler"
+	"github.com/future-architect/code-diaper/formatter"
+	"github.com/future-architect/code-diaper/store"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// fakeCrawler returns the repositories after the delay, or the error.
+type fakeCrawler struct {
+	delay time.Duration
+	repos crawler.Repositories
+	err   error
+}
+
+func (c fakeCrawler) Search(ctx context.Context, words []string) (crawler.Repositories, crawler.Coverage, error) {
+	select {
+	case <-time.After(c.delay):
+	case <-ctx.Done():
+		return nil, crawler.Coverage{}, ctx.Err()
+	}
+	if c.err != nil {
+		return nil, crawler.Coverage{}, c.err
+	}
+	return c.repos, crawler.Coverage{Total: len(c.repos), Fetched: len(c.repos)}, nil
+}
+
+func (c fakeCrawler) Enrich(ctx context.Context, repos crawler.Repositories) (crawler.Repositories, error) {
+	return repos, nil
+}
+
+// useFakeCrawlers makes the crawler of each search by its first query.
+func useFakeCrawlers(t *testing.T, crawlers map[condition.Sentence]fakeCrawler) func() {
+	original := newCrawler
+	newCrawler = func(ops condition.Options, s condition.Search) (crawler.Crawler, error) {
+		c, ok := crawlers[s.QueryList[0]]
+		if !ok {
+			t.Fatalf("unexpected search: %v", s.QueryList)
+		}
+		return c, nil
+	}
+	return func() { newCrawler = original }
+}
+
+// memoryStore keeps the findings in memory.
+type memoryStore struct {
+	findings store.Findings
+}
+
+func (s *memoryStore) Load(ctx context.Context) (store.Findings, error) {
+	return append(store.Findings{}, s.findings...), nil
+}
+
+func (s *memoryStore) Save(ctx context.Context, fs store.Findings) error {
+	s.findings = append(store.Findings{}, fs...)
+	return nil
+}
+
+func hit(repoName string, paths ...string) crawler.Repositories {
+	repo := crawler.Repository{URL: "https://github.com/ghost/" + repoName, Owner: "ghost", Name: repoName}
+	for _, p := range paths {
+		repo.HitFiles = append(repo.HitFiles, crawler.File{URL: repo.URL + "/blob/aaa/" + p, Path: p, Fragments: []string{"Copyright 2019 " + repoName}})
+	}
+	return crawler.Repositories{repo}
+}
+
+func searches(queries ...condition.Sentence) []condition.Search {
+	var result []condition.Search
+	for _, q := range queries {
+		result = append(result, condition.Search{QueryList: []condition.Sentence{q}})
+	}
+	return result
+}
+
+func queries(resultList []formatter.SearchResult) []string {
+	var result []string
+	for _, sr := range resultList {
+		result = append(result, sr.Query)
+	}
+	return result
+}
+
+func TestRunSearchListOrder(t *testing.T) {
+	defer useFakeCrawlers(t, map[condition.Sentence]fakeCrawler{
+		"Copyright+slow":   {delay: 50 * time.Millisecond, repos: hit("slow", "a.go")},
+		"Copyright+fast":   {repos: hit("fast", "a.go")},
+		"Copyright+middle": {delay: 20 * time.Millisecond, repos: hit("middle", "a.go")},
+	})()
+
+	for _, concurrency := range []int{1, 3} {
+		ops := condition.Options{Concurrency: concurrency}
+		actual, err := runSearchList(context.Background(), ops, searches("Copyright+slow", "Copyright+fast", "Copyright+middle"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		expected := []string{"Copyright+slow", "Copyright+fast", "Copyright+middle"}
+		if !reflect.DeepEqual(queries(actual), expected) {
+			t.Errorf("concurrency %v got: %v\nwant: %v", concurrency, queries(actual), expected)
+		}
+		if actual[0].Repos[0].Name != "slow" || actual[2].Repos[0].Name != "middle" {
+			t.Errorf("concurrency %v got: %+v", concurrency, actual)
+		}
+	}
+}
+
+func TestRunSearchListCancel(t *testing.T) {
+	failure := errors.New("bad credentials")
+	defer useFakeCrawlers(t, map[condition.Sentence]fakeCrawler{
+		"Copyright+hang": {delay: time.Hour},
+		"Copyright+fail": {delay: 10 * time.Millisecond, err: failure},
+	})()
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := runSearchList(context.Background(), condition.Options{Concurrency: 2}, searches("Copyright+hang", "Copyright+fail"))
+		done <- err
+	}()
+
+	select {
+	case err := <-done:
+		// the original error is reported, not context.Canceled of the stopped search
+		if err != failure {
+			t.Errorf("got: %v\nwant: %v", err, failure)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("the other search is not canceled")
+	}
+}
+
+func TestReconcile(t *testing.T) {
+	day1 := time.Date(2019, 8, 1, 0, 0, 0, 0, time.UTC)
+	result := func(repos crawler.Repositories) []formatter.SearchResult {
+		return []formatter.SearchResult{formatter.NewSearchResult("github.com", "Copyright", repos, crawler.Coverage{Total: 1, Fetched: 1})}
+	}
+
+	tests := []struct {
+		name      string
+		runs      []crawler.Repositories
+		reportAll bool
+		repos     int      // repositories reported by the last run
+		new       int      // findings first found by the last run
+		resolved  []string // file URLs resolved by the last run
+		closed    []string // repositories all of whose findings are resolved by the last run
+	}{
+		{name: "first run", runs: []crawler.Repositories{hit("leak", "a.go", "b.go")}, repos: 1, new: 2},
+		{name: "present", runs: []crawler.Repositories{hit("leak", "a.go"), hit("leak", "a.go")}, repos: 0},
+		{name: "present with reportAll", runs: []crawler.Repositories{hit("leak", "a.go"), hit("leak", "a.go")}, reportAll: true, repos: 1},
+		{name: "partly resolved", runs: []crawler.Repositories{hit("leak", "a.go", "b.go"), hit("leak", "a.go")}, repos: 0,
+			resolved: []string{"https://github.com/ghost/leak/blob/aaa/b.go"}},
+		{name: "resolved", runs: []crawler.Repositories{hit("leak", "a.go"), nil}, repos: 0,
+			resolved: []string{"https://github.com/ghost/leak/blob/aaa/a.go"}, closed: []string{"https://github.com/ghost/leak"}},
+		{name: "new again", runs: []crawler.Repositories{hit("leak", "a.go"), nil, hit("leak", "a.go")}, repos: 1, new: 1},
+	}
+	for _, tt := range tests {
+		st := &memoryStore{}
+		var actual []formatter.SearchResult
+		for i, repos := range tt.runs {
+			var err error
+			actual, err = reconcile(context.Background(), st, result(repos), tt.reportAll, day1.AddDate(0, 0, i))
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+		sr := actual[0]
+		if len(sr.Repos) != tt.repos || sr.HitCount != tt.repos {
+			t.Errorf("%v got: %+v\nwant: %v repositories", tt.name, sr.Repos, tt.repos)
+		}
+		if len(sr.New) != tt.new {
+			t.Errorf("%v got: %v\nwant: %v new findings", tt.name, sr.New, tt.new)
+		}
+		if resolved := sr.Resolved.FileURLs(); !reflect.DeepEqual(resolved, tt.resolved) {
+			t.Errorf("%v got: %v\nwant: %v", tt.name, resolved, tt.resolved)
+		}
+		if !reflect.DeepEqual(sr.ResolvedRepos, tt.closed) {
+			t.Errorf("%v got: %v\nwant: %v", tt.name, sr.ResolvedRepos, tt.closed)
+		}
+	}
+}
+
+func TestReconcileTriaged(t *testing.T) {
+	now := time.Date(2019, 8, 1, 0, 0, 0, 0, time.UTC)
+	repos := hit("leak", "a.go", "b.go")
+	stored := store.NewFindings("Copyright", repos, now)
+	stored, err := stored.SetTriage(stored[0].ID, store.TriageFalsePositive, "", now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	st := &memoryStore{findings: stored}
+
+	actual, err := reconcile(context.Background(), st, []formatter.SearchResult{formatter.NewSearchResult("github.com", "Copyright", repos, crawler.Coverage{})}, true, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	files := actual[0].Repos[0].HitFiles
+	if len(files) != 1 || files[0].Path != "b.go" {
+		t.Errorf("got: %+v", files)
+	}
+}
@@ -0,0 +1,79 @@
+/**
+ * Copyright (c) 2019-present Future Corporation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package diaper
+
+import (
+	"context"
+	"fmt"
+	"github.com/future-architect/code-diaper/crawler"
+	"github.com/future-architect/code-diaper/fingerprint"
+	"log"
+	"sync"
+)
+
+// maxSimilar is the number of internal files shown for a hit file.
+const maxSimilar = 3
+
+var (
+	indexesMu sync.Mutex
+	indexes   = map[string]*fingerprint.Index{}
+)
+
+// loadIndex loads the fingerprint index once, because it is shared by the searches.
+func loadIndex(path string) (*fingerprint.Index, error) {
+	indexesMu.Lock()
+	defer indexesMu.Unlock()
+
+	if idx, ok := indexes[path]; ok {
+		return idx, nil
+	}
+	idx, err := fingerprint.Load(path)
+	if err != nil {
+		return nil, fmt.Errorf("fingerprint index %v: %v", path, err)
+	}
+	indexes[path] = idx
+	return idx, nil
+}
+
+// annotateSimilar downloads the hit files and records the internal files copied to them.
+// Only the files found by the queries are compared, so a copy whose header is stripped is not found by the search.
+// A file that can not be downloaded is left as it is.
+func annotateSimilar(ctx context.Context, idx *fingerprint.Index, fetcher crawler.ContentFetcher, repos crawler.Repositories, maxSize int, threshold float64) crawler.Repositories {
+	result := make(crawler.Repositories, 0, len(repos))
+	for _, r := range repos {
+		files := make(crawler.Files, 0, len(r.HitFiles))
+		for _, file := range r.HitFiles {
+			content, err := fetcher.FetchContent(ctx, r, file, maxSize)
+			if err != nil {
+				log.Printf("similarity is not checked %v: %v\n", file.URL, err)
+				files = append(files, file)
+				continue
+			}
+
+			file.Similar = nil
+			for i, m := range idx.Match(string(content), threshold) {
+				if i >= maxSimilar {
+					break
+				}
+				file.Similar = append(file.Similar, crawler.Similarity{Path: m.Path, Score: m.Score, Lines: m.Lines})
+			}
+			files = append(files, file)
+		}
+		r.HitFiles = files
+		result = append(result, r)
+	}
+	return result
+}
@@ -18,12 +18,17 @@ package function
 import (
 	"cloud.google.com/go/pubsub"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"github.com/future-architect/code-diaper/condition"
 	"github.com/future-architect/code-diaper/diaper"
 	"github.com/future-architect/code-diaper/reporter"
+	"github.com/future-architect/code-diaper/store"
 	"github.com/kelseyhightower/envconfig"
 	"golang.org/x/net/context"
 	"log"
+	"net/http"
+	"sync"
 )
 
 // CloudFunction entry point
@@ -41,25 +46,65 @@ func Subscribe(ctx context.Context, msg *pubsub.Message) error {
 	}
 
 	ops := envOps.Override(msgOps)
+	if len(ops.Reporters) == 0 {
+		// the function has always notified Slack
+		ops.Reporters = condition.List{reporter.NameSlack}
+	}
 
-	message, err := diaper.Run(ctx, ops)
+	reporters, err := reporter.New(ops)
 	if err != nil {
 		return err
 	}
 
-	slack := reporter.NewSlackReporter(ops.SlackToken, ops.SlackChannel)
-
-	ts, err := slack.Post(ctx, message.Summary)
+	result, err := diaper.Run(ctx, ops)
 	if err != nil {
 		return err
 	}
 
-	for _, v := range message.Details {
-		if err := slack.PostThread(ctx, ts, v); err != nil {
-			return err
-		}
+	if err := reporter.ReportAll(ctx, reporters, result); err != nil {
+		return err
 	}
 
 	log.Println("finish")
 	return nil
 }
+
+var (
+	slackTriageOnce    sync.Once
+	slackTriageHandler http.Handler
+	slackTriageErr     error
+)
+
+// SlackTriage is the HTTP entry point for the triage buttons of Slack messages.
+// Set it to Interactivity Request URL of the Slack app.
+// STORE_PATH must be an object of Cloud Storage (gs://bucket/object) shared with the Subscribe function.
+// A local file is refused, because it is not seen by the other instances and is lost when the instance stops.
+func SlackTriage(w http.ResponseWriter, r *http.Request) {
+	// one handler per instance, so that its lock serializes concurrent clicks
+	slackTriageOnce.Do(func() {
+		var ops condition.Options
+		if slackTriageErr = envconfig.Process("", &ops); slackTriageErr != nil {
+			return
+		}
+		if ops.SlackSigningSecret == "" || ops.StorePath == "" {
+			slackTriageErr = errors.New("required parameter: SLACK_SIGNING_SECRET and STORE_PATH")
+			return
+		}
+		if !store.IsShared(ops.StorePath) {
+			slackTriageErr = fmt.Errorf("file store is not supported in Cloud Functions: STORE_PATH must be gs://bucket/object: %v", ops.StorePath)
+			return
+		}
+		st, err := store.NewStore(context.Background(), ops.StorePath)
+		if err != nil {
+			slackTriageErr = err
+			return
+		}
+		slackTriageHandler = reporter.NewSlackTriageHandler(ops.SlackSigningSecret, st)
+	})
+	if slackTriageErr != nil {
+		log.Println(slackTriageErr)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	slackTriageHandler.ServeHTTP(w, r)
+}
@@ -60,7 +60,11 @@ func (f skipFilter) doBySentence(s condition.Sentence, rs crawler.Repositories)
 	if s == "" {
 		return rs
 	}
-	searchWords := s.Parse()
+	match, err := fragmentMatcher(s)
+	if err != nil {
+		// already validated before searching. not to miss leaks, nothing is filtered
+		return rs
+	}
 
 	result := make(crawler.Repositories, 0, len(rs))
 
@@ -69,17 +73,16 @@ func (f skipFilter) doBySentence(s condition.Sentence, rs crawler.Repositories)
 		var files []crawler.File
 
 		for _, f := range v.HitFiles {
+			if f.Verified {
+				// already matched against the whole content
+				files = append(files, f)
+				continue
+			}
 			var containsAllKeyWord = false
 			for _, fragment := range f.Fragments {
-
-				// When there is line break, split the search target
-				lines := strings.Split(fragment, "\n")
-
-				for _, searchLine := range lines {
-					if allContains(searchLine, searchWords) {
-						containsAllKeyWord = true
-						break
-					}
+				if match(fragment) {
+					containsAllKeyWord = true
+					break
 				}
 			}
 			if containsAllKeyWord {
@@ -181,6 +184,97 @@ func (f skipFilter) matchOwnerName(repo crawler.Repository) bool {
 	return false
 }
 
+// matchWindow is the number of lines matched together, so that a header whose words are split into lines is found.
+// Both the fragments and the verified contents are matched by the same windows.
+const matchWindow = 3
+
+// fragmentMatcher returns the function that reports whether the fragment matches the sentence.
+// Boolean expressions are evaluated against the whole fragment, the others against every window of matchWindow lines.
+func fragmentMatcher(s condition.Sentence) (func(fragment string) bool, error) {
+	if s.IsExpression() {
+		e, err := s.Compile()
+		if err != nil {
+			return nil, err
+		}
+		return e.Match, nil
+	}
+
+	match, err := windowMatcher(s)
+	if err != nil {
+		return nil, err
+	}
+	return func(fragment string) bool {
+		// When there is line break, split the search target
+		lines := strings.Split(fragment, "\n")
+		for i := range lines {
+			end := i + matchWindow
+			if end > len(lines) {
+				end = len(lines)
+			}
+			if len(match(lines[i:end])) > 0 {
+				return true
+			}
+		}
+		return false
+	}, nil
+}
+
+// windowMatcher returns the function that returns the 0-based indexes of the lines taking part in the match,
+// or nil if the lines do not match. The window matches a plain sentence if its first line contains all words,
+// or if the words appear in the order of the sentence across the lines.
+func windowMatcher(s condition.Sentence) (func(window []string) []int, error) {
+	if s.IsPattern() {
+		re, err := s.Regexp()
+		if err != nil {
+			return nil, err
+		}
+		return func(window []string) []int {
+			text := strings.Join(window, "\n")
+			var result []int
+			for _, m := range re.FindAllStringIndex(text, -1) {
+				for n := strings.Count(text[:m[0]], "\n"); n <= strings.Count(text[:m[1]], "\n"); n++ {
+					result = append(result, n)
+				}
+			}
+			return result
+		}, nil
+	}
+
+	var words []string
+	for _, w := range s.Parse() {
+		if w != "" {
+			words = append(words, w)
+		}
+	}
+	return func(window []string) []int {
+		if allContains(window[0], words) {
+			return []int{0}
+		}
+		// words split into lines must keep their order, not to join unrelated words of the next lines
+		if !containsInOrder(strings.Join(window, "\n"), words) {
+			return nil
+		}
+		var result []int
+		for i, line := range window {
+			if anyContains(line, words) {
+				result = append(result, i)
+			}
+		}
+		return result
+	}, nil
+}
+
+func containsInOrder(text string, words []string) bool {
+	for _, w := range words {
+		i := strings.Index(text, w)
+		if i < 0 {
+			return false
+		}
+		text = text[i+len(w):]
+	}
+	return true
+}
+
 func allContains(fragment string, searchWords []string) bool {
 	for _, search := range searchWords {
 		if !strings.Contains(fragment, search) {
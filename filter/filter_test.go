@@ -18,7 +18,9 @@ package filter
 import (
 	"github.com/future-architect/code-diaper/condition"
 	"github.com/future-architect/code-diaper/crawler"
+	"github.com/future-architect/code-diaper/store"
 	"testing"
+	"time"
 )
 
 var input1 = crawler.Repositories(
@@ -89,3 +91,104 @@ func TestSearchWord(t *testing.T) {
 	}
 
 }
+
+func TestSearchWordWrapped(t *testing.T) {
+	repos := func(fragment string) crawler.Repositories {
+		return crawler.Repositories{{URL: "https://github.com/ghost/dummy", Owner: "ghost", Name: "dummy",
+			HitFiles: crawler.Files{{URL: "https://github.com/ghost/dummy/a.go", Fragments: []string{fragment}}}}}
+	}
+	tests := []struct {
+		sentence condition.Sentence
+		fragment string
+		expected int
+	}{
+		{"Copyright+2019+Future+Corporation", "/*\n * Copyright 2019\n * Future Corporation\n */", 1},
+		{`/Copyright 2019\n \* Future/`, "/*\n * Copyright 2019\n * Future Corporation\n */", 1},
+		// the same words out of order across lines
+		{"Copyright+2019+Future+Corporation", "// Future Corporation\npackage a\n// Copyright 2019", 0},
+		// more than 3 lines apart
+		{"Copyright+2019+Future+Corporation", "// Copyright 2019\n\n\n// Future Corporation", 0},
+	}
+	for _, tt := range tests {
+		actual := NewSkipFilter([]condition.Sentence{tt.sentence}, []string{}, []string{}, []string{}).Do(repos(tt.fragment))
+		if len(actual) != tt.expected {
+			t.Errorf("%v %q got: %v\nwant: %v", tt.sentence, tt.fragment, len(actual), tt.expected)
+		}
+	}
+}
+
+func TestSearchPattern(t *testing.T) {
+	expected1 := 1
+	actual1 := NewSkipFilter([]condition.Sentence{`/Copyright \(c\) 20[0-9]{2}-2019 Example/`}, []string{}, []string{}, []string{}).Do(input1)
+	if len(actual1) != expected1 {
+		t.Errorf("got: %v\nwant: %v", actual1, expected1)
+	}
+
+	// words on different lines do not match
+	expected2 := 0
+	actual2 := NewSkipFilter([]condition.Sentence{`/FutureTask.*Example/`}, []string{}, []string{}, []string{}).Do(input1)
+	if len(actual2) != expected2 {
+		t.Errorf("got: %v\nwant: %v", actual2, expected2)
+	}
+}
+
+func TestSearchExpression(t *testing.T) {
+	// words on different lines match because expression is evaluated against the whole fragment
+	expected1 := 1
+	actual1 := NewSkipFilter([]condition.Sentence{`FutureTask AND Example NOT Apache`}, []string{}, []string{}, []string{}).Do(input1)
+	if len(actual1) != expected1 {
+		t.Errorf("got: %v\nwant: %v", actual1, expected1)
+	}
+
+	expected2 := 0
+	actual2 := NewSkipFilter([]condition.Sentence{`Copyright AND NOT "Example Corporation"`}, []string{}, []string{}, []string{}).Do(input1)
+	if len(actual2) != expected2 {
+		t.Errorf("got: %v\nwant: %v", actual2, expected2)
+	}
+}
+
+func TestTriageFilter(t *testing.T) {
+	now := time.Now()
+	findings := store.NewFindings("q", input1, now)
+
+	findings, _ = findings.SetTriage(store.FindingID("https://github.com/ghost/dummy1", crawler.File{URL: "https://github.com/ghost/dummy1/fizz1.md"}, "abcdef"), store.TriageFalsePositive, "", now)
+	findings, _ = findings.SetTriage(store.FindingID("https://github.com/ghost/dummy1", crawler.File{URL: "https://github.com/ghost/dummy1/buzz1.md"}, "abcdef"), store.TriageAcknowledged, "", now)
+	findings, _ = findings.SetTriage(store.FindingID("https://github.com/ghost/dummy2", crawler.File{URL: "https://github.com/ghost/dummy2/fizz2.md"}, "abcdef"), store.TriageRemediated, "", now)
+
+	actual := NewTriageFilter(findings).Do(input1)
+
+	// dummy1 is skipped because all files are triaged as false positive or acknowledged
+	expected := 3
+	if len(actual) != expected {
+		t.Errorf("got: %v\nwant: %v", actual, expected)
+	}
+	if len(actual[0].HitFiles) != 2 {
+		t.Errorf("got: %v\nwant: %v", actual[0].HitFiles, 2)
+	}
+}
+
+func TestTriageFilterAfterPush(t *testing.T) {
+	day1 := time.Date(2019, 8, 1, 0, 0, 0, 0, time.UTC)
+	day2 := day1.AddDate(0, 0, 1)
+	pushed := func(sha, fragment string) crawler.Repositories {
+		return crawler.Repositories{{URL: "https://github.com/ghost/dummy", HitFiles: crawler.Files{
+			{URL: "https://github.com/ghost/dummy/blob/" + sha + "/LICENSE", Path: "LICENSE", Fragments: []string{fragment}},
+		}}}
+	}
+
+	first := store.Reconcile(store.Findings{}, store.NewFindings("q", pushed("aaa", "Copyright 2019 Example"), day1), func(store.Finding) bool { return true }, day1)
+	triaged, err := first.All.SetTriage(first.All[0].ID, store.TriageFalsePositive, "sample code", day1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// a new commit changes the SHA of the URL, and the search API returns the fragment with other spaces
+	input := pushed("bbb", "Copyright  2019\tExample")
+	second := store.Reconcile(triaged, store.NewFindings("q", input, day2), func(store.Finding) bool { return true }, day2)
+	if len(second.New) != 0 {
+		t.Errorf("got: %+v\nwant: no new findings", second.New)
+	}
+	if actual := NewTriageFilter(second.All).Do(input); len(actual) != 0 {
+		t.Errorf("got: %+v\nwant: suppressed", actual)
+	}
+}
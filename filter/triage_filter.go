@@ -0,0 +1,63 @@
+/**
+ * Copyright (c) 2019-present Future Corporation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package filter
+
+import (
+	"github.com/future-architect/code-diaper/crawler"
+	"github.com/future-architect/code-diaper/store"
+)
+
+type triageFilter struct {
+	findings store.Findings
+}
+
+// NewTriageFilter returns a filter that skips the fragments triaged as false positive or acknowledged.
+func NewTriageFilter(findings store.Findings) Filter {
+	return triageFilter{
+		findings: findings,
+	}
+}
+
+func (f triageFilter) Do(rs crawler.Repositories) crawler.Repositories {
+	var result crawler.Repositories
+	for _, r := range rs {
+
+		var files crawler.Files
+		for _, file := range r.HitFiles {
+			var fragments []string
+			for _, fragment := range file.Fragments {
+				if !f.suppressed(r, file, fragment) {
+					fragments = append(fragments, fragment)
+				}
+			}
+			if len(fragments) > 0 {
+				file.Fragments = fragments
+				files = append(files, file)
+			}
+		}
+
+		if len(files) > 0 {
+			r.HitFiles = files
+			result = append(result, r)
+		}
+	}
+	return result
+}
+
+func (f triageFilter) suppressed(r crawler.Repository, file crawler.File, fragment string) bool {
+	idx := f.findings.Index(store.FindingID(r.URL, file, fragment))
+	return idx != -1 && f.findings[idx].Triage.Suppresses()
+}
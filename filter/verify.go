@@ -0,0 +1,159 @@
+/**
+ * Copyright (c) 2019-present Future Corporation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package filter
+
+import (
+	"context"
+	"github.com/future-architect/code-diaper/condition"
+	"github.com/future-architect/code-diaper/crawler"
+	"log"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Verifier re-runs the sentences over the whole content of the hit files instead of the fragments of the search API,
+// so that a match outside the fragments is confirmed and its line numbers are recorded.
+type Verifier struct {
+	sList   []condition.Sentence
+	fetcher crawler.ContentFetcher
+	maxSize int
+}
+
+func NewVerifier(sList []condition.Sentence, fetcher crawler.ContentFetcher, maxSize int) *Verifier {
+	return &Verifier{
+		sList:   sList,
+		fetcher: fetcher,
+		maxSize: maxSize,
+	}
+}
+
+// Do drops the files whose content does not match and marks the others as verified.
+// If the content can not be downloaded, e.g. too large, the file is left to the fragment based filter.
+func (v *Verifier) Do(ctx context.Context, rs crawler.Repositories) crawler.Repositories {
+	var result crawler.Repositories
+	for _, r := range rs {
+
+		var files crawler.Files
+		for _, file := range r.HitFiles {
+			content, err := v.fetcher.FetchContent(ctx, r, file, v.maxSize)
+			if err != nil {
+				log.Printf("not verified %v: %v\n", file.URL, err)
+				files = append(files, file)
+				continue
+			}
+
+			lines, ok, err := v.match(string(content))
+			if err != nil {
+				// already validated before searching. not to miss leaks, the file is left as it is
+				files = append(files, file)
+				continue
+			}
+			if !ok {
+				continue
+			}
+			file.Verified = true
+			file.Lines = lines
+			files = append(files, file)
+		}
+
+		if len(files) > 0 {
+			r.HitFiles = files
+			result = append(result, r)
+		}
+	}
+	return result
+}
+
+// match reports whether the content matches all sentences, and returns the matched line numbers.
+// Boolean expressions are evaluated against the whole content, and the lines containing their keywords are returned.
+// The other sentences are evaluated against every window of matchWindow lines like the fragments.
+func (v *Verifier) match(content string) ([]int, bool, error) {
+	lines := strings.Split(content, "\n")
+	matched := map[int]bool{}
+
+	for _, s := range v.sList {
+		if s == "" {
+			continue
+		}
+		if s.IsExpression() {
+			e, err := s.Compile()
+			if err != nil {
+				return nil, false, err
+			}
+			if !e.Match(content) {
+				return nil, false, nil
+			}
+			keywords := s.Keywords()
+			var patterns []*regexp.Regexp
+			for _, p := range s.Patterns() {
+				if re, err := regexp.Compile(p); err == nil {
+					patterns = append(patterns, re)
+				}
+			}
+			for i, line := range lines {
+				if anyContains(line, keywords) || anyMatch(line, patterns) {
+					matched[i+1] = true
+				}
+			}
+			continue
+		}
+
+		match, err := windowMatcher(s)
+		if err != nil {
+			return nil, false, err
+		}
+		found := false
+		for i := range lines {
+			end := i + matchWindow
+			if end > len(lines) {
+				end = len(lines)
+			}
+			for _, n := range match(lines[i:end]) {
+				matched[i+n+1] = true
+				found = true
+			}
+		}
+		if !found {
+			return nil, false, nil
+		}
+	}
+
+	result := make([]int, 0, len(matched))
+	for n := range matched {
+		result = append(result, n)
+	}
+	sort.Ints(result)
+	return result, true, nil
+}
+
+func anyMatch(line string, patterns []*regexp.Regexp) bool {
+	for _, re := range patterns {
+		if re.MatchString(line) {
+			return true
+		}
+	}
+	return false
+}
+
+func anyContains(line string, words []string) bool {
+	for _, w := range words {
+		if strings.Contains(line, w) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,117 @@
+/**
+ * Copyright (c) 2019-present Future Corporation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package filter
+
+import (
+	"context"
+	"errors"
+	"github.com/future-architect/code-diaper/condition"
+	"github.com/future-architect/code-diaper/crawler"
+	"reflect"
+	"testing"
+)
+
+type fakeFetcher map[string]string
+
+func (f fakeFetcher) FetchContent(ctx context.Context, repo crawler.Repository, file crawler.File, maxSize int) ([]byte, error) {
+	content, ok := f[file.URL]
+	if !ok {
+		return nil, errors.New("not found")
+	}
+	if len(content) > maxSize {
+		return nil, crawler.ErrTooLarge
+	}
+	return []byte(content), nil
+}
+
+func TestVerifier(t *testing.T) {
+	input := crawler.Repositories{{
+		URL: "https://github.com/ghost/dummy", Owner: "ghost", Name: "dummy",
+		HitFiles: crawler.Files{
+			{URL: "header.go", Fragments: []string{"/*\n * Copyright 2019"}},
+			{URL: "other.go", Fragments: []string{"Copyright 2019"}},
+			{URL: "large.go", Fragments: []string{"Copyright 2019"}},
+			{URL: "deleted.go", Fragments: []string{"Copyright 2019"}},
+		},
+	}}
+	fetcher := fakeFetcher{
+		"header.go": "/*\n * Copyright 2019\n * Future Corporation\n */\npackage a\n// Future Corporation\n",
+		"other.go":  "// Copyright 2019 Example\n",
+		"large.go":  "// Copyright 2019 Future Corporation\n" + string(make([]byte, 100)),
+	}
+
+	sList := []condition.Sentence{"Future Corporation"}
+	actual := NewVerifier(sList, fetcher, 100).Do(context.Background(), input)
+
+	if len(actual) != 1 || len(actual[0].HitFiles) != 3 {
+		t.Fatalf("got: %+v", actual)
+	}
+	header := actual[0].HitFiles[0]
+	if !header.Verified || !reflect.DeepEqual(header.Lines, []int{3, 6}) {
+		t.Errorf("got: %+v", header)
+	}
+	for _, f := range actual[0].HitFiles[1:] {
+		if f.Verified {
+			t.Errorf("got: %+v\nwant: not verified", f)
+		}
+	}
+
+	// the fragment does not contain "Future Corporation", but the verified file is kept
+	filtered := NewSkipFilter(sList, nil, nil, nil).Do(actual)
+	if len(filtered) != 1 || len(filtered[0].HitFiles) != 1 || filtered[0].HitFiles[0].URL != "header.go" {
+		t.Errorf("got: %+v", filtered)
+	}
+}
+
+func TestVerifierExpression(t *testing.T) {
+	input := crawler.Repositories{{
+		URL: "https://github.com/ghost/dummy", Owner: "ghost", Name: "dummy",
+		HitFiles: crawler.Files{
+			{URL: "a.go", Fragments: []string{"Copyright 2019"}},
+			{URL: "b.go", Fragments: []string{"Copyright 2019"}},
+		},
+	}}
+	fetcher := fakeFetcher{
+		"a.go": "// Copyright 2019\n// Future\npackage a\n",
+		"b.go": "// Copyright 2019\n// Future\n// Apache License\n",
+	}
+
+	actual := NewVerifier([]condition.Sentence{`Copyright AND Future NOT "Apache License"`}, fetcher, 1024).Do(context.Background(), input)
+	if len(actual) != 1 || len(actual[0].HitFiles) != 1 || !reflect.DeepEqual(actual[0].HitFiles[0].Lines, []int{1, 2}) {
+		t.Errorf("got: %+v", actual)
+	}
+}
+
+func TestVerifierMultiLine(t *testing.T) {
+	input := crawler.Repositories{{
+		URL: "https://github.com/ghost/dummy", Owner: "ghost", Name: "dummy",
+		HitFiles: crawler.Files{
+			{URL: "header.go", Fragments: []string{"Copyright 2019"}},
+			{URL: "apart.go", Fragments: []string{"Copyright 2019"}},
+		},
+	}}
+	fetcher := fakeFetcher{
+		"header.go": "/*\n * Copyright 2019\n * Future Corporation\n */\npackage a\n",
+		"apart.go":  "// Copyright 2019\npackage a\n\nfunc A() {}\n\n// Future Corporation\n",
+	}
+
+	for _, s := range []condition.Sentence{"Copyright+2019+Future+Corporation", `/Copyright 2019\n \* Future/`} {
+		actual := NewVerifier([]condition.Sentence{s}, fetcher, 1024).Do(context.Background(), input)
+		if len(actual) != 1 || len(actual[0].HitFiles) != 1 || !reflect.DeepEqual(actual[0].HitFiles[0].Lines, []int{2, 3}) {
+			t.Errorf("%v got: %+v", s, actual)
+		}
+	}
+}
@@ -0,0 +1,153 @@
+/**
+ * Copyright (c) 2019-present Future Corporation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+// Package fingerprint detects copied source code without the copyright header by winnowing.
+// http://theory.stanford.edu/~aiken/publications/papers/sigmod03.pdf
+package fingerprint
+
+import (
+	"hash/fnv"
+	"strings"
+	"unicode"
+)
+
+const (
+	// K is the number of tokens of a k-gram. Copies shorter than K tokens are not detected.
+	K = 8
+
+	// W is the window size of winnowing. Copies of at least K+W-1 tokens share a fingerprint.
+	W = 4
+)
+
+// Fingerprint is the hash of a k-gram selected by winnowing. Line is the line of the first token.
+type Fingerprint struct {
+	Hash uint64
+	Line int
+}
+
+type token struct {
+	text string
+	line int
+}
+
+// Fingerprints returns the fingerprints of the source code. Comments and spaces are ignored,
+// identifiers are lower cased and numbers are replaced, so that reformatting does not change the result.
+func Fingerprints(src string) []Fingerprint {
+	tokens := tokenize(src)
+	if len(tokens) < K {
+		return nil
+	}
+
+	hashes := make([]uint64, len(tokens)-K+1)
+	for i := range hashes {
+		h := fnv.New64a()
+		for _, t := range tokens[i : i+K] {
+			h.Write([]byte(t.text))
+			h.Write([]byte{0})
+		}
+		hashes[i] = h.Sum64()
+	}
+
+	// select the minimum hash of each window. the rightmost one on ties
+	var result []Fingerprint
+	selected := -1
+	w := W
+	if len(hashes) < w {
+		w = len(hashes)
+	}
+	for start := 0; start+w <= len(hashes); start++ {
+		min := start
+		for i := start; i < start+w; i++ {
+			if hashes[i] <= hashes[min] {
+				min = i
+			}
+		}
+		if min != selected {
+			selected = min
+			result = append(result, Fingerprint{Hash: hashes[min], Line: tokens[min].line})
+		}
+	}
+	return result
+}
+
+// tokenize splits the source code into identifiers, numbers, string literals and symbols.
+func tokenize(src string) []token {
+	var result []token
+	rs := []rune(src)
+	line := 1
+	lineStart := true // only spaces are found from the start of the line
+
+	for i := 0; i < len(rs); {
+		r := rs[i]
+		switch {
+		case r == '\n':
+			line++
+			lineStart = true
+			i++
+			continue
+		case unicode.IsSpace(r):
+			i++
+			continue
+		case r == '/' && i+1 < len(rs) && rs[i+1] == '/', r == '#' && lineStart:
+			// line comment. "#" is a comment of shell, Python, YAML and so on only at the start of the line
+			for i < len(rs) && rs[i] != '\n' {
+				i++
+			}
+			continue
+		case r == '/' && i+1 < len(rs) && rs[i+1] == '*':
+			i += 2
+			for i < len(rs) && !(rs[i] == '*' && i+1 < len(rs) && rs[i+1] == '/') {
+				if rs[i] == '\n' {
+					line++
+				}
+				i++
+			}
+			i += 2
+			continue
+		}
+		lineStart = false
+
+		start := i
+		switch {
+		case r == '_' || unicode.IsLetter(r):
+			for i < len(rs) && (rs[i] == '_' || unicode.IsLetter(rs[i]) || unicode.IsDigit(rs[i])) {
+				i++
+			}
+			result = append(result, token{text: strings.ToLower(string(rs[start:i])), line: line})
+		case unicode.IsDigit(r):
+			for i < len(rs) && (rs[i] == '.' || unicode.IsLetter(rs[i]) || unicode.IsDigit(rs[i])) {
+				i++
+			}
+			result = append(result, token{text: "0", line: line})
+		case r == '"' || r == '\'' || r == '`':
+			i++
+			for i < len(rs) && rs[i] != r && rs[i] != '\n' {
+				if rs[i] == '\\' {
+					i++
+				}
+				i++
+			}
+			i++
+			if i > len(rs) {
+				i = len(rs)
+			}
+			result = append(result, token{text: string(rs[start:i]), line: line})
+		default:
+			i++
+			result = append(result, token{text: string(r), line: line})
+		}
+	}
+	return result
+}
@@ -0,0 +1,150 @@
+/**
+ * Copyright (c) 2019-present Future Corporation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package fingerprint
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const internal = `/*
+ * Copyright 2019 Future Corporation
+ */
+package billing
+
+// Calculate returns the amount of the invoice.
+func Calculate(items []Item, rate float64) (int, error) {
+	total := 0
+	for _, item := range items {
+		if item.Price < 0 {
+			return 0, fmt.Errorf("invalid price: %v", item.Price)
+		}
+		total += item.Price * item.Quantity
+	}
+	tax := int(float64(total) * rate)
+	return total + tax, nil
+}
+`
+
+// the header and the comments are stripped, and the code is reformatted.
+const leaked = `package main
+
+func Calculate(items []Item, rate float64) (int, error) {
+    total := 0
+    for _, item := range items {
+        if item.Price < 0 { return 0, fmt.Errorf("invalid price: %v", item.Price) }
+        total += item.Price * item.Quantity
+    }
+    tax := int(float64(total) * rate)
+    return total + tax, nil
+}
+`
+
+const unrelated = `package main
+
+import "net/http"
+
+func main() {
+	http.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("hello"))
+	})
+	http.ListenAndServe(":8080", nil)
+}
+`
+
+func TestFingerprints(t *testing.T) {
+	hashes := func(fps []Fingerprint) []uint64 {
+		var result []uint64
+		for _, v := range fps {
+			result = append(result, v.Hash)
+		}
+		return result
+	}
+	a := hashes(Fingerprints("x := 1 // comment\nreturn foo(x, 2)\n/* block\ncomment */ y := bar(x)"))
+	b := hashes(Fingerprints("x:=100\nreturn   FOO(x,3)\n\n y := bar(x)"))
+	if len(a) == 0 || !reflect.DeepEqual(a, b) {
+		t.Errorf("got: %v\nwant: %v", b, a)
+	}
+
+	if actual := Fingerprints("too short"); actual != nil {
+		t.Errorf("got: %v\nwant: nil", actual)
+	}
+}
+
+func TestTokenize(t *testing.T) {
+	var actual []string
+	for _, v := range tokenize("# comment\nFoo(\"a // b\", 0x1F) // c") {
+		actual = append(actual, v.text)
+	}
+	expected := []string{"foo", "(", `"a // b"`, ",", "0", ")"}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("got: %q\nwant: %q", actual, expected)
+	}
+}
+
+func TestIndex(t *testing.T) {
+	dir, err := ioutil.TempDir("", "codediaper")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	src := filepath.Join(dir, "app")
+	if err := os.MkdirAll(filepath.Join(src, "billing"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(src, "billing", "calc.go"), []byte(internal), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(src, "main.go"), []byte(unrelated), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	built, err := Build([]string{src})
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(dir, "index.json")
+	if err := built.Save(path); err != nil {
+		t.Fatal(err)
+	}
+	idx, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(idx.Files) != 2 {
+		t.Fatalf("got: %+v", idx.Files)
+	}
+
+	matches := idx.Match(leaked, 0.5)
+	if len(matches) != 1 || matches[0].Path != "app/billing/calc.go" || matches[0].Score < 0.8 {
+		t.Fatalf("got: %+v", matches)
+	}
+	if lines := matches[0].Lines; len(lines) == 0 || lines[0] < 3 {
+		t.Errorf("got: %v", lines)
+	}
+
+	if actual := idx.Match(strings.Replace(unrelated, "hello", "bye", 1), 0.5); len(actual) != 1 || actual[0].Path != "app/main.go" {
+		t.Errorf("got: %+v", actual)
+	}
+	if actual := idx.Match("package main\n\nfunc main() {\n\tprintln(\"nothing in common with the index\")\n}\n", 0.5); len(actual) != 0 {
+		t.Errorf("got: %+v\nwant: []", actual)
+	}
+}
@@ -0,0 +1,240 @@
+/**
+ * Copyright (c) 2019-present Future Corporation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package fingerprint
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+)
+
+const (
+	// MinShared is the number of fingerprints a copy must share with an indexed file.
+	MinShared = 5
+
+	// maxFileSize is the byte size limit of the files to be indexed.
+	maxFileSize = 1024 * 1024
+
+	// binaryCheckSize is the byte size checked for NUL to detect binary files, same as git.
+	binaryCheckSize = 8000
+)
+
+// Index is the fingerprints of the private source trees.
+type Index struct {
+	Files  []IndexedFile
+	hashes map[uint64][]int // hash -> indexes of Files
+}
+
+// indexJSON is the file format of Index. JSON keys must be strings, so hashes are hex.
+type indexJSON struct {
+	Files  []IndexedFile    `json:"files"`
+	Hashes map[string][]int `json:"hashes"`
+}
+
+type IndexedFile struct {
+	Path         string `json:"path"`
+	Fingerprints int    `json:"fingerprints"` // number of distinct fingerprints
+}
+
+// Match is an indexed file similar to the candidate.
+type Match struct {
+	Path   string  `json:"path"`
+	Score  float64 `json:"score"`  // shared fingerprints / fingerprints of the smaller file
+	Shared int     `json:"shared"` // number of shared fingerprints
+	Lines  []int   `json:"lines"`  // lines of the candidate that share fingerprints
+}
+
+func NewIndex() *Index {
+	return &Index{
+		hashes: map[uint64][]int{},
+	}
+}
+
+// Build indexes the text files under the directories. Paths are relative to the parent of each directory,
+// e.g. "app/src/main.go" for the directory "app". ".git" directories are skipped.
+func Build(dirs []string) (*Index, error) {
+	idx := NewIndex()
+	for _, dir := range dirs {
+		abs, err := filepath.Abs(dir)
+		if err != nil {
+			return nil, err
+		}
+		err = filepath.Walk(abs, func(path string, info os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+			if info.IsDir() {
+				if info.Name() == ".git" {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			if !info.Mode().IsRegular() || info.Size() > maxFileSize {
+				return nil
+			}
+			b, err := ioutil.ReadFile(path)
+			if err != nil {
+				return err
+			}
+			if isBinary(b) {
+				return nil
+			}
+			rel, err := filepath.Rel(filepath.Dir(abs), path)
+			if err != nil {
+				return err
+			}
+			idx.Add(filepath.ToSlash(rel), string(b))
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return idx, nil
+}
+
+// Add indexes the source code. Files without fingerprints are ignored.
+func (idx *Index) Add(path, src string) {
+	hashes := distinct(Fingerprints(src))
+	if len(hashes) == 0 {
+		return
+	}
+	i := len(idx.Files)
+	idx.Files = append(idx.Files, IndexedFile{Path: path, Fingerprints: len(hashes)})
+	for h := range hashes {
+		idx.hashes[h] = append(idx.hashes[h], i)
+	}
+}
+
+// Match returns the indexed files that share at least MinShared fingerprints with the source code
+// and whose score is threshold or more, in descending order of the score.
+func (idx *Index) Match(src string, threshold float64) []Match {
+	fps := Fingerprints(src)
+	hashes := distinct(fps)
+	if len(hashes) == 0 {
+		return nil
+	}
+
+	shared := map[int]map[uint64]bool{}
+	for h := range hashes {
+		for _, i := range idx.hashes[h] {
+			if shared[i] == nil {
+				shared[i] = map[uint64]bool{}
+			}
+			shared[i][h] = true
+		}
+	}
+
+	var result []Match
+	for i, hs := range shared {
+		if len(hs) < MinShared {
+			continue
+		}
+		smaller := len(hashes)
+		if idx.Files[i].Fingerprints < smaller {
+			smaller = idx.Files[i].Fingerprints
+		}
+		score := float64(len(hs)) / float64(smaller)
+		if score < threshold {
+			continue
+		}
+		result = append(result, Match{
+			Path:   idx.Files[i].Path,
+			Score:  score,
+			Shared: len(hs),
+			Lines:  linesOf(fps, hs),
+		})
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Score != result[j].Score {
+			return result[i].Score > result[j].Score
+		}
+		return result[i].Path < result[j].Path
+	})
+	return result
+}
+
+// Save writes the index as JSON.
+func (idx *Index) Save(path string) error {
+	v := indexJSON{Files: idx.Files, Hashes: map[string][]int{}}
+	for h, files := range idx.hashes {
+		v.Hashes[strconv.FormatUint(h, 16)] = files
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, b, 0644)
+}
+
+// Load reads the index written by Save.
+func Load(path string) (*Index, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var v indexJSON
+	if err := json.Unmarshal(b, &v); err != nil {
+		return nil, err
+	}
+	idx := NewIndex()
+	idx.Files = v.Files
+	for key, files := range v.Hashes {
+		h, err := strconv.ParseUint(key, 16, 64)
+		if err != nil {
+			return nil, err
+		}
+		idx.hashes[h] = files
+	}
+	return idx, nil
+}
+
+func distinct(fps []Fingerprint) map[uint64]bool {
+	result := map[uint64]bool{}
+	for _, fp := range fps {
+		result[fp.Hash] = true
+	}
+	return result
+}
+
+func linesOf(fps []Fingerprint, hashes map[uint64]bool) []int {
+	var lines []int
+	for _, fp := range fps {
+		if hashes[fp.Hash] {
+			lines = append(lines, fp.Line)
+		}
+	}
+	sort.Ints(lines)
+
+	var result []int
+	for _, n := range lines {
+		if len(result) == 0 || result[len(result)-1] != n {
+			result = append(result, n)
+		}
+	}
+	return result
+}
+
+func isBinary(b []byte) bool {
+	if len(b) > binaryCheckSize {
+		b = b[:binaryCheckSize]
+	}
+	return bytes.IndexByte(b, 0) >= 0
+}
@@ -0,0 +1,66 @@
+/**
+ * Copyright (c) 2019-present Future Corporation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package formatter
+
+import (
+	"fmt"
+	"github.com/future-architect/code-diaper/crawler"
+	"sort"
+	"strings"
+)
+
+// forkWeight is the weight of a fork in the exposure. A fork is another copy of the code that remains after the takedown.
+const forkWeight = 3
+
+// Exposure estimates how widely the repository has been seen.
+func Exposure(repo crawler.Repository) int {
+	return repo.Stars + repo.Forks*forkWeight
+}
+
+// SortByExposure sorts the repositories from the most exposed one. Recently pushed ones come first in the same exposure.
+// The order is kept if the metadata is unknown, e.g. a local repository.
+func SortByExposure(repos crawler.Repositories) crawler.Repositories {
+	result := append(crawler.Repositories{}, repos...)
+	sort.SliceStable(result, func(i, j int) bool {
+		if ei, ej := Exposure(result[i]), Exposure(result[j]); ei != ej {
+			return ei > ej
+		}
+		return result[i].PushedAt.After(result[j].PushedAt)
+	})
+	return result
+}
+
+// ExposureNote describes the metadata of the repository, e.g. "★12 フォーク3 Go 作成: 2019-07-01 最終push: 2019-08-01".
+// It is empty if nothing is known.
+func ExposureNote(repo crawler.Repository) string {
+	if repo.CreatedAt.IsZero() && repo.PushedAt.IsZero() && Exposure(repo) == 0 && repo.Language == "" && !repo.Archived {
+		return ""
+	}
+	notes := []string{fmt.Sprintf("★%d", repo.Stars), fmt.Sprintf("フォーク%d", repo.Forks)}
+	if repo.Language != "" {
+		notes = append(notes, repo.Language)
+	}
+	if !repo.CreatedAt.IsZero() {
+		notes = append(notes, "作成: "+repo.CreatedAt.Format("2006-01-02"))
+	}
+	if !repo.PushedAt.IsZero() {
+		notes = append(notes, "最終push: "+repo.PushedAt.Format("2006-01-02"))
+	}
+	if repo.Archived {
+		notes = append(notes, "アーカイブ済み")
+	}
+	return strings.Join(notes, " ")
+}
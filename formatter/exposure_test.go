@@ -0,0 +1,64 @@
+/**
+ * Copyright (c) 2019-present Future Corporation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package formatter
+
+import (
+	"github.com/future-architect/code-diaper/crawler"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSortByExposure(t *testing.T) {
+	input := crawler.Repositories{
+		{URL: "quiet"},
+		{URL: "starred", Stars: 5},
+		{URL: "forked", Forks: 2},
+		{URL: "pushed", Stars: 5, PushedAt: time.Date(2019, 8, 1, 0, 0, 0, 0, time.UTC)},
+	}
+	var actual []string
+	for _, v := range SortByExposure(input) {
+		actual = append(actual, v.URL)
+	}
+	expected := "forked,pushed,starred,quiet"
+	if strings.Join(actual, ",") != expected {
+		t.Errorf("got: %v\nwant: %v", strings.Join(actual, ","), expected)
+	}
+	if input[0].URL != "quiet" {
+		t.Errorf("input is modified: %v", input)
+	}
+}
+
+func TestExposureNote(t *testing.T) {
+	repo := crawler.Repository{Stars: 12, Forks: 3, Language: "Go", Archived: true,
+		CreatedAt: time.Date(2019, 7, 1, 0, 0, 0, 0, time.UTC), PushedAt: time.Date(2019, 8, 1, 0, 0, 0, 0, time.UTC)}
+	expected := "★12 フォーク3 Go 作成: 2019-07-01 最終push: 2019-08-01 アーカイブ済み"
+	if actual := ExposureNote(repo); actual != expected {
+		t.Errorf("got: %v\nwant: %v", actual, expected)
+	}
+	if actual := ExposureNote(crawler.Repository{URL: "local"}); actual != "" {
+		t.Errorf("got: %v\nwant: empty", actual)
+	}
+
+	detail, err := FmtDetail(NewSearchResult("", "test1", crawler.Repositories{{Owner: "ghost", Name: "dummy", Stars: 1,
+		HitFiles: crawler.Files{{URL: "a.go", Fragments: []string{"Copyright"}}}}}, crawler.Coverage{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(detail, "test1の詳細結果:ghost/dummy (★1 フォーク0)\n") {
+		t.Errorf("got: %v", detail)
+	}
+}
@@ -17,41 +17,71 @@ package formatter
 
 import (
 	"bytes"
+	"fmt"
 	"github.com/future-architect/code-diaper/crawler"
+	"github.com/future-architect/code-diaper/store"
+	"strconv"
 	"strings"
 	"text/template"
 )
 
 const TopMessage = `
 {{ range $i, $sr := . -}}
-	{{- $sr.Query}}の検索結果: {{$sr.HitCount}}件
+	{{- if $sr.Host }}[{{ $sr.Host }}] {{ end }}{{- $sr.Query}}の検索結果: {{$sr.HitCount}}件
+	{{- if $sr.Coverage.Incomplete }} ※一部のみ取得({{ $sr.Coverage.Fetched }}/{{ $sr.Coverage.Total }}){{ end }}
+	{{- if $sr.Resolved }} 解消: {{ len $sr.Resolved.FileURLs }}件{{ end }}
 {{ end -}}
 `
 
 const DetailMessage = `
 {{ range $i, $repo := .Repos -}}
-{{ $.Query -}}の詳細結果:{{- $repo.Owner }}/{{- $repo.Name }}{{printf "\n" }}
+{{ if $.Host }}[{{ $.Host }}] {{ end }}{{ $.Query -}}の詳細結果:{{- $repo.Owner }}/{{- $repo.Name }}{{ with exposure $repo }} ({{ . }}){{ end }}{{printf "\n" }}
 	{{- range $j, $file := $repo.HitFiles -}}
 		{{- if lt $j 3 -}}
--->{{ $file.URL }}{{printf "\n" }}
+-->{{ $file.URL }} (ID: {{ findingIDs $repo $file }}{{ if $file.Lines }}, 行: {{ lineNumbers $file }}{{ end }}){{printf "\n" }}
+			{{- range $similar := $file.Similar -}}
+   類似: {{ $similar.Path }} ({{ printf "%.0f" (percent $similar.Score) }}%){{printf "\n" }}
+			{{- end -}}
+			{{- with $file.Attribution -}}
+				{{- with .LastCommit }}   最終コミット: {{ commit . }}{{printf "\n" }}{{ end -}}
+				{{- with .Introduced }}   追加コミット: {{ commit . }}{{printf "\n" }}{{ end -}}
+			{{- end -}}
 		{{- else if eq $j 3 -}}
 -->...{{printf "\n" }}
 		{{- end -}}
 	{{- end -}}
 {{ end -}}
+{{ if .Resolved -}}
+{{ if .Host }}[{{ .Host }}] {{ end }}{{ .Query -}}の解消済み{{printf "\n" }}
+	{{- range $j, $url := .Resolved.FileURLs -}}
+-->{{ $url }}{{printf "\n" }}
+	{{- end -}}
+{{ end -}}
 `
 
 type SearchResult struct {
-	Query    string
-	Repos    crawler.Repositories
-	HitCount int
+	Host     string               `json:"host"` // host name of the code hosting service. e.g. github.com
+	Query    string               `json:"query"`
+	Keywords []string             `json:"keywords,omitempty"` // words highlighted in the fragments
+	Patterns []string             `json:"patterns,omitempty"` // regular expressions highlighted in the fragments
+	Repos    crawler.Repositories `json:"repositories"`
+	HitCount int                  `json:"hit_count"`
+	Coverage crawler.Coverage     `json:"coverage"`
+	Resolved store.Findings       `json:"resolved,omitempty"` // findings not found any more since the previous run
+	New      []string             `json:"new,omitempty"`      // IDs of the findings first found in this run. Set only with the finding store
+	MailTo   []string             `json:"-"`                  // recipients of the email digest in addition to the default ones
+
+	// ResolvedRepos are the repository URLs that had resolved findings in this run and have no other findings any more.
+	ResolvedRepos []string `json:"resolved_repositories,omitempty"`
 }
 
-func NewSearchResult(searchWord string, reps crawler.Repositories) SearchResult {
+func NewSearchResult(host, searchWord string, reps crawler.Repositories, coverage crawler.Coverage) SearchResult {
 	return SearchResult{
+		Host:     host,
 		Query:    searchWord,
-		Repos:    reps,
+		Repos:    SortByExposure(reps),
 		HitCount: len(reps),
+		Coverage: coverage,
 	}
 }
 
@@ -62,9 +92,52 @@ func FmtTop(list []SearchResult) (string, error) {
 	return strings.TrimSpace(buff.String()), err
 }
 
+var funcMap = template.FuncMap{
+	"findingIDs":  findingIDs,
+	"lineNumbers": lineNumbers,
+	"percent":     percent,
+	"commit":      FmtCommit,
+	"exposure":    ExposureNote,
+}
+
+// findingIDs returns comma separated IDs of the fragments of the file. IDs are used for triage.
+func findingIDs(repo crawler.Repository, file crawler.File) string {
+	var ids []string
+	for _, fragment := range file.Fragments {
+		ids = append(ids, store.FindingID(repo.URL, file, fragment))
+	}
+	return strings.Join(ids, ", ")
+}
+
+// lineNumbers returns comma separated line numbers matched in the whole content of the file.
+func lineNumbers(file crawler.File) string {
+	var lines []string
+	for _, n := range file.Lines {
+		lines = append(lines, strconv.Itoa(n))
+	}
+	return strings.Join(lines, ", ")
+}
+
+// FmtCommit returns the short SHA, the date and the author of the commit.
+func FmtCommit(c crawler.Commit) string {
+	sha := c.SHA
+	if len(sha) > 7 {
+		sha = sha[:7]
+	}
+	result := fmt.Sprintf("%v %v %v", sha, c.Date.Format("2006-01-02"), c.Author)
+	if c.Login != "" {
+		result += " (@" + c.Login + ")"
+	}
+	return result
+}
+
+func percent(score float64) float64 {
+	return score * 100
+}
+
 func FmtDetail(sr SearchResult) (string, error) {
 	var buff bytes.Buffer
-	topTemplate := template.Must(template.New("detail").Parse(DetailMessage))
+	topTemplate := template.Must(template.New("detail").Funcs(funcMap).Parse(DetailMessage))
 	err := topTemplate.Execute(&buff, sr)
 	return strings.TrimSpace(buff.String()), err
 }
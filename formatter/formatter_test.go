@@ -17,8 +17,10 @@ package formatter
 
 import (
 	"github.com/future-architect/code-diaper/crawler"
+	"github.com/future-architect/code-diaper/store"
 	"strings"
 	"testing"
+	"time"
 )
 
 var input1 = []SearchResult{
@@ -77,8 +79,8 @@ func TestFmtTop(t *testing.T) {
 
 func TestFmtDetail(t *testing.T) {
 	expected := strings.Join([]string{"test1の詳細結果:ghost/dummy-repo1",
-		"-->https://github.com/ghost/dummy-repo1/dummy1.md",
-		"-->https://github.com/ghost/dummy-repo1/dummy2.md"}, "\n")
+		"-->https://github.com/ghost/dummy-repo1/dummy1.md (ID: c95ac5aef243, bf0825cdcb3f)",
+		"-->https://github.com/ghost/dummy-repo1/dummy2.md (ID: 9566ff96e8ff, dce40afe5387)"}, "\n")
 
 	actual, err := FmtDetail(input1[0])
 	if err != nil {
@@ -124,9 +126,9 @@ var input2 = []SearchResult{
 
 func TestFmtDetailOmmit(t *testing.T) {
 	expected := strings.Join([]string{"test1の詳細結果:ghost/dummy-repo1",
-		"-->https://github.com/ghost/dummy-repo1/dummy1.md",
-		"-->https://github.com/ghost/dummy-repo1/dummy2.md",
-		"-->https://github.com/ghost/dummy-repo1/dummy3.md",
+		"-->https://github.com/ghost/dummy-repo1/dummy1.md (ID: c95ac5aef243, bf0825cdcb3f)",
+		"-->https://github.com/ghost/dummy-repo1/dummy2.md (ID: 9566ff96e8ff, dce40afe5387)",
+		"-->https://github.com/ghost/dummy-repo1/dummy3.md (ID: 1e1bac34dc3f, e16e034cdf08)",
 		"-->..."}, "\n")
 
 	actual, err := FmtDetail(input2[0])
@@ -138,3 +140,92 @@ func TestFmtDetailOmmit(t *testing.T) {
 		t.Errorf("got: %v\nwant: %v", actual, expected)
 	}
 }
+
+func TestFmtTopWithHost(t *testing.T) {
+	input := []SearchResult{
+		{Host: "github.com", Query: "test1", HitCount: 1},
+		{Host: "github.example.com", Query: "test1", HitCount: 2},
+	}
+	expected := strings.Join([]string{"[github.com] test1の検索結果: 1件", "[github.example.com] test1の検索結果: 2件"}, "\n")
+
+	actual, err := FmtTop(input)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if actual != expected {
+		t.Errorf("got: %v\nwant: %v", actual, expected)
+	}
+}
+
+func TestFmtTopIncomplete(t *testing.T) {
+	input := []SearchResult{
+		{Query: "test1", HitCount: 1, Coverage: crawler.Coverage{Total: 1500, Fetched: 1000, Incomplete: true}},
+	}
+	expected := "test1の検索結果: 1件 ※一部のみ取得(1000/1500)"
+
+	actual, err := FmtTop(input)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if actual != expected {
+		t.Errorf("got: %v\nwant: %v", actual, expected)
+	}
+}
+
+func TestFmtDetailResolved(t *testing.T) {
+	input := SearchResult{
+		Query: "test1",
+		Resolved: store.Findings{
+			{FileURL: "https://github.com/ghost/dummy-repo1/dummy1.md", Fragment: "detect dummy1-1"},
+			{FileURL: "https://github.com/ghost/dummy-repo1/dummy1.md", Fragment: "detect dummy1-2"},
+			{FileURL: "https://github.com/ghost/dummy-repo1/dummy2.md", Fragment: "detect dummy2-1"},
+		},
+	}
+	expected := strings.Join([]string{"test1の解消済み",
+		"-->https://github.com/ghost/dummy-repo1/dummy1.md",
+		"-->https://github.com/ghost/dummy-repo1/dummy2.md"}, "\n")
+
+	actual, err := FmtDetail(input)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if actual != expected {
+		t.Errorf("got: %v\nwant: %v", actual, expected)
+	}
+
+	top, err := FmtTop([]SearchResult{input})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if top != "test1の検索結果: 0件 解消: 2件" {
+		t.Errorf("got: %v", top)
+	}
+}
+
+func TestFmtDetailAttribution(t *testing.T) {
+	input := SearchResult{
+		Query: "test1",
+		Repos: crawler.Repositories{{URL: "https://github.com/ghost/dummy", Owner: "ghost", Name: "dummy",
+			HitFiles: crawler.Files{{URL: "https://github.com/ghost/dummy/a.go", Fragments: []string{"Copyright"},
+				Attribution: &crawler.Attribution{
+					LastCommit: &crawler.Commit{SHA: "ccc1234567", Date: time.Date(2019, 8, 2, 9, 0, 0, 0, time.UTC), Author: "Ghost <ghost@example.com>", Login: "ghost"},
+					Introduced: &crawler.Commit{SHA: "bbb1234567", Date: time.Date(2019, 8, 1, 9, 0, 0, 0, time.UTC), Author: "Taro <taro@example.com>"},
+				}}}}},
+	}
+
+	actual, err := FmtDetail(input)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, expected := range []string{
+		"   最終コミット: ccc1234 2019-08-02 Ghost <ghost@example.com> (@ghost)\n",
+		"   追加コミット: bbb1234 2019-08-01 Taro <taro@example.com>",
+	} {
+		if !strings.Contains(actual, expected) {
+			t.Errorf("got: %v\nwant: %v", actual, expected)
+		}
+	}
+}
@@ -0,0 +1,176 @@
+/**
+ * Copyright (c) 2019-present Future Corporation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package formatter
+
+import (
+	"github.com/future-architect/code-diaper/crawler"
+	"github.com/future-architect/code-diaper/store"
+	"html/template"
+	"io"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// HTMLMessage is a self-contained report. It has no external resources, so that it can be attached as a file.
+const HTMLMessage = `<!DOCTYPE html>
+<html lang="ja">
+<head>
+<meta charset="utf-8">
+<title>code-diaper 検索結果</title>
+<style>
+body { font-family: sans-serif; margin: 2em; color: #24292e; }
+h2 { border-bottom: 1px solid #e1e4e8; padding-bottom: .3em; }
+h3 { color: #586069; font-size: 1em; }
+.repo { margin: 1em 0 1em 1em; }
+.file { margin-left: 1em; }
+pre { background: #f6f8fa; padding: .5em; overflow-x: auto; white-space: pre-wrap; }
+mark { background: #fff5b1; font-weight: bold; }
+.id { color: #6a737d; font-size: .8em; }
+.warn { color: #b08800; }
+</style>
+</head>
+<body>
+<h1>code-diaper 検索結果</h1>
+<p>{{ .StartedAt.Format "2006-01-02 15:04:05" }} - {{ .FinishedAt.Format "2006-01-02 15:04:05" }}</p>
+{{ range $sr := .Searches }}
+<section>
+<h2>{{ if $sr.Host }}[{{ $sr.Host }}] {{ end }}{{ $sr.Query }}の検索結果: {{ $sr.HitCount }}件
+{{- if $sr.Coverage.Incomplete }} <span class="warn">※一部のみ取得({{ $sr.Coverage.Fetched }}/{{ $sr.Coverage.Total }})</span>{{ end }}</h2>
+{{- range $group := forkGroups $sr.Repos }}
+<h3>{{ if $group.ForkSource }}フォーク元: {{ $group.ForkSource }}{{ else }}フォーク元なし{{ end }}</h3>
+{{- range $repo := $group.Repos }}
+<div class="repo">
+<h4><a href="{{ $repo.URL }}">{{ $repo.Owner }}/{{ $repo.Name }}</a>{{ with exposure $repo }} <span class="id">{{ . }}</span>{{ end }}</h4>
+{{- range $file := $repo.HitFiles }}
+<div class="file">
+<a href="{{ $file.URL }}">{{ $file.URL }}</a>
+{{- range $similar := $file.Similar }}
+<div class="id">類似: {{ $similar.Path }} ({{ printf "%.0f" (percent $similar.Score) }}%)</div>
+{{- end }}
+{{- with $file.Attribution }}
+{{- with .LastCommit }}
+<div class="id">最終コミット: {{ if .URL }}<a href="{{ .URL }}">{{ commit . }}</a>{{ else }}{{ commit . }}{{ end }}</div>
+{{- end }}
+{{- with .Introduced }}
+<div class="id">追加コミット: {{ if .URL }}<a href="{{ .URL }}">{{ commit . }}</a>{{ else }}{{ commit . }}{{ end }}</div>
+{{- end }}
+{{- end }}
+{{- range $fragment := $file.Fragments }}
+<div class="id">ID: {{ findingID $repo $file $fragment }}</div>
+<pre>{{ highlight $fragment $sr.Keywords $sr.Patterns }}</pre>
+{{- end }}
+</div>
+{{- end }}
+</div>
+{{- end }}
+{{- end }}
+{{- if $sr.Resolved }}
+<h3>解消済み</h3>
+<ul>
+{{- range $url := $sr.Resolved.FileURLs }}
+<li><a href="{{ $url }}">{{ $url }}</a></li>
+{{- end }}
+</ul>
+{{- end }}
+</section>
+{{- end }}
+</body>
+</html>
+`
+
+// ForkGroup is the repositories forked from the same source. ForkSource is empty for the repositories that are not forks.
+type ForkGroup struct {
+	ForkSource string
+	Repos      crawler.Repositories
+}
+
+// forkGroups groups the repositories by the fork source. Repositories that are not forks come first.
+func forkGroups(repos crawler.Repositories) []ForkGroup {
+	var result []ForkGroup
+	index := map[string]int{}
+	for _, r := range repos {
+		i, ok := index[r.ForkSource]
+		if !ok {
+			i = len(result)
+			index[r.ForkSource] = i
+			result = append(result, ForkGroup{ForkSource: r.ForkSource})
+		}
+		result[i].Repos = append(result[i].Repos, r)
+	}
+	sort.SliceStable(result, func(i, j int) bool {
+		return result[i].ForkSource == "" && result[j].ForkSource != ""
+	})
+	return result
+}
+
+// highlight escapes the fragment and surrounds the keywords and the matches of the patterns with <mark>.
+// Keywords are matched case-insensitively like the search API, and patterns as they are written.
+func highlight(fragment string, keywords, patterns []string) template.HTML {
+	var quoted []string
+	for _, v := range keywords {
+		if v != "" {
+			quoted = append(quoted, regexp.QuoteMeta(v))
+		}
+	}
+	// the longest keyword wins when keywords overlap
+	sort.SliceStable(quoted, func(i, j int) bool {
+		return len(quoted[i]) > len(quoted[j])
+	})
+
+	var alternatives []string
+	if len(quoted) > 0 {
+		alternatives = append(alternatives, "(?i:"+strings.Join(quoted, "|")+")")
+	}
+	for _, v := range patterns {
+		if _, err := regexp.Compile(v); err == nil {
+			alternatives = append(alternatives, "(?:"+v+")")
+		}
+	}
+	if len(alternatives) == 0 {
+		return template.HTML(template.HTMLEscapeString(fragment))
+	}
+	re := regexp.MustCompile(strings.Join(alternatives, "|"))
+
+	var b strings.Builder
+	last := 0
+	for _, m := range re.FindAllStringIndex(fragment, -1) {
+		b.WriteString(template.HTMLEscapeString(fragment[last:m[0]]))
+		b.WriteString("<mark>")
+		b.WriteString(template.HTMLEscapeString(fragment[m[0]:m[1]]))
+		b.WriteString("</mark>")
+		last = m[1]
+	}
+	b.WriteString(template.HTMLEscapeString(fragment[last:]))
+	return template.HTML(b.String())
+}
+
+var htmlFuncMap = template.FuncMap{
+	"forkGroups": forkGroups,
+	"highlight":  highlight,
+	"percent":    percent,
+	"commit":     FmtCommit,
+	"exposure":   ExposureNote,
+	"findingID": func(repo crawler.Repository, file crawler.File, fragment string) string {
+		return store.FindingID(repo.URL, file, fragment)
+	},
+}
+
+// FmtHTML renders every repository, file and fragment of the result.
+func FmtHTML(w io.Writer, r ScanResult) error {
+	htmlTemplate := template.Must(template.New("html").Funcs(htmlFuncMap).Parse(HTMLMessage))
+	return htmlTemplate.Execute(w, r)
+}
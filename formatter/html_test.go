@@ -0,0 +1,82 @@
+/**
+ * Copyright (c) 2019-present Future Corporation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package formatter
+
+import (
+	"bytes"
+	"github.com/future-architect/code-diaper/crawler"
+	"strings"
+	"testing"
+)
+
+func TestHighlight(t *testing.T) {
+	tests := []struct {
+		fragment string
+		keywords []string
+		patterns []string
+		expected string
+	}{
+		{"// Copyright 2019 Future", []string{"copyright", "Future"}, nil, "// <mark>Copyright</mark> 2019 <mark>Future</mark>"},
+		{"<b>Future Corporation</b>", []string{"Future", "Future Corporation"}, nil, "&lt;b&gt;<mark>Future Corporation</mark>&lt;/b&gt;"},
+		{"a < b", nil, nil, "a &lt; b"},
+		// the "c" of the copyright sign is not highlighted alone
+		{"// Copyright (c) 2019 Future, abc", nil, []string{`Copyright \(c\) 20[0-9]{2} Future`}, "// <mark>Copyright (c) 2019 Future</mark>, abc"},
+		{"// copyright 2019 Future", nil, []string{`Copyright 20[0-9]{2}`}, "// copyright 2019 Future"},
+		{"// Copyright 2019 Future", []string{"future"}, []string{`20[0-9]{2}`}, "// Copyright <mark>2019</mark> <mark>Future</mark>"},
+	}
+	for _, tt := range tests {
+		actual := string(highlight(tt.fragment, tt.keywords, tt.patterns))
+		if actual != tt.expected {
+			t.Errorf("got: %v\nwant: %v", actual, tt.expected)
+		}
+	}
+}
+
+func TestFmtHTML(t *testing.T) {
+	searches := []SearchResult{
+		{
+			Query:    "test1",
+			Keywords: []string{"dummy1"},
+			Repos: crawler.Repositories{
+				{URL: "https://github.com/ghost/fork1", Owner: "ghost", Name: "fork1", ForkSource: "future/origin",
+					HitFiles: crawler.Files{{URL: "https://github.com/ghost/fork1/a.go", Fragments: []string{"detect dummy1-1"}}}},
+				{URL: "https://github.com/ghost/dummy-repo1", Owner: "ghost", Name: "dummy-repo1",
+					HitFiles: crawler.Files{{URL: "https://github.com/ghost/dummy-repo1/b.go", Fragments: []string{"<script>dummy1</script>"}}}},
+			},
+			HitCount: 2,
+		},
+	}
+
+	var buff bytes.Buffer
+	if err := FmtHTML(&buff, ScanResult{Searches: searches}); err != nil {
+		t.Fatal(err)
+	}
+	actual := buff.String()
+
+	for _, want := range []string{
+		"test1の検索結果: 2件",
+		"detect <mark>dummy1</mark>-1",
+		"&lt;script&gt;<mark>dummy1</mark>&lt;/script&gt;",
+		"フォーク元: future/origin",
+	} {
+		if !strings.Contains(actual, want) {
+			t.Errorf("got: %v\nwant: contains %v", actual, want)
+		}
+	}
+	if strings.Index(actual, "フォーク元なし") > strings.Index(actual, "フォーク元: future/origin") {
+		t.Errorf("repositories that are not forks must come first")
+	}
+}
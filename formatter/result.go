@@ -0,0 +1,167 @@
+/**
+ * Copyright (c) 2019-present Future Corporation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package formatter
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/future-architect/code-diaper/store"
+	"io"
+	"time"
+)
+
+const (
+	FormatText   = "text"
+	FormatJSON   = "json"
+	FormatNDJSON = "ndjson"
+	FormatSARIF  = "sarif"
+	FormatHTML   = "html"
+)
+
+// ScanResult is the result of one run. Renderers such as text templates and JSON are applied to it.
+type ScanResult struct {
+	StartedAt  time.Time      `json:"started_at"`
+	FinishedAt time.Time      `json:"finished_at"`
+	Searches   []SearchResult `json:"searches"`
+}
+
+// Record is a line of NDJSON. It is a hit fragment or a resolved finding, so that each line can be ingested alone.
+type Record struct {
+	ID            string    `json:"id"`
+	Status        string    `json:"status"` // "hit" or "resolved"
+	ScannedAt     time.Time `json:"scanned_at"`
+	Host          string    `json:"host"`
+	Query         string    `json:"query"`
+	RepositoryURL string    `json:"repository_url"`
+	Owner         string    `json:"owner,omitempty"`
+	Name          string    `json:"name,omitempty"`
+	ForkSource    string    `json:"fork_source,omitempty"`
+	Exposure      int       `json:"exposure,omitempty"`
+	Stars         int       `json:"stars,omitempty"`
+	Forks         int       `json:"forks,omitempty"`
+	Archived      bool      `json:"archived,omitempty"`
+	FileURL       string    `json:"file_url"`
+	Fragment      string    `json:"fragment"`
+}
+
+// StatusHit is the status of the fragments found by this run.
+const StatusHit = "hit"
+
+// Records flattens the result into fragments.
+func (r ScanResult) Records() []Record {
+	var result []Record
+	for _, sr := range r.Searches {
+		for _, repo := range sr.Repos {
+			for _, file := range repo.HitFiles {
+				for _, fragment := range file.Fragments {
+					result = append(result, Record{
+						ID:            store.FindingID(repo.URL, file, fragment),
+						Status:        StatusHit,
+						ScannedAt:     r.FinishedAt,
+						Host:          sr.Host,
+						Query:         sr.Query,
+						RepositoryURL: repo.URL,
+						Owner:         repo.Owner,
+						Name:          repo.Name,
+						ForkSource:    repo.ForkSource,
+						Exposure:      Exposure(repo),
+						Stars:         repo.Stars,
+						Forks:         repo.Forks,
+						Archived:      repo.Archived,
+						FileURL:       file.URL,
+						Fragment:      fragment,
+					})
+				}
+			}
+		}
+		for _, f := range sr.Resolved {
+			result = append(result, Record{
+				ID:            f.ID,
+				Status:        string(store.StatusResolved),
+				ScannedAt:     r.FinishedAt,
+				Host:          sr.Host,
+				Query:         sr.Query,
+				RepositoryURL: f.RepositoryURL,
+				FileURL:       f.FileURL,
+				Fragment:      f.Fragment,
+			})
+		}
+	}
+	return result
+}
+
+// FmtText renders the summary and the details of each search by the text templates.
+func FmtText(r ScanResult) (string, []string, error) {
+	summary, err := FmtTop(r.Searches)
+	if err != nil {
+		return "", nil, err
+	}
+
+	var details []string
+	for _, v := range r.Searches {
+		if len(v.Repos) == 0 && len(v.Resolved) == 0 {
+			continue
+		}
+		detail, err := FmtDetail(v)
+		if err != nil {
+			return "", nil, err
+		}
+		details = append(details, detail)
+	}
+	return summary, details, nil
+}
+
+func FmtJSON(w io.Writer, r ScanResult) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(r)
+}
+
+func FmtNDJSON(w io.Writer, r ScanResult) error {
+	enc := json.NewEncoder(w)
+	for _, v := range r.Records() {
+		if err := enc.Encode(v); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Write renders the result in the format to w.
+func Write(w io.Writer, format string, r ScanResult) error {
+	switch format {
+	case "", FormatText:
+		summary, details, err := FmtText(r)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintln(w, summary)
+		for _, v := range details {
+			fmt.Fprintln(w, v)
+		}
+		return nil
+	case FormatJSON:
+		return FmtJSON(w, r)
+	case FormatNDJSON:
+		return FmtNDJSON(w, r)
+	case FormatSARIF:
+		return FmtSARIF(w, r)
+	case FormatHTML:
+		return FmtHTML(w, r)
+	default:
+		return fmt.Errorf("unknown format: %v", format)
+	}
+}
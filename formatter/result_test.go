@@ -0,0 +1,92 @@
+/**
+ * Copyright (c) 2019-present Future Corporation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package formatter
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"github.com/future-architect/code-diaper/crawler"
+	"github.com/future-architect/code-diaper/store"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestFmtJSON(t *testing.T) {
+	input := ScanResult{
+		StartedAt:  time.Date(2019, 8, 1, 9, 0, 0, 0, time.UTC),
+		FinishedAt: time.Date(2019, 8, 1, 9, 1, 0, 0, time.UTC),
+		Searches:   input1,
+	}
+
+	var buff bytes.Buffer
+	if err := FmtJSON(&buff, input); err != nil {
+		t.Fatal(err)
+	}
+
+	var actual ScanResult
+	if err := json.Unmarshal(buff.Bytes(), &actual); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(actual, input) {
+		t.Errorf("got: %+v\nwant: %+v", actual, input)
+	}
+}
+
+func TestFmtNDJSON(t *testing.T) {
+	searches := append([]SearchResult{}, input1...)
+	searches[1].Resolved = store.Findings{
+		{ID: "c95ac5aef243", Queries: []string{"test2"}, RepositoryURL: "https://github.com/ghost/dummy-repo3", FileURL: "https://github.com/ghost/dummy-repo3/dummy4.md", Fragment: "detect dummy4-1", Status: store.StatusResolved},
+	}
+	input := ScanResult{FinishedAt: time.Date(2019, 8, 1, 9, 1, 0, 0, time.UTC), Searches: searches}
+
+	var buff bytes.Buffer
+	if err := FmtNDJSON(&buff, input); err != nil {
+		t.Fatal(err)
+	}
+
+	var actual []Record
+	scanner := bufio.NewScanner(&buff)
+	for scanner.Scan() {
+		var r Record
+		if err := json.Unmarshal(scanner.Bytes(), &r); err != nil {
+			t.Fatal(err)
+		}
+		actual = append(actual, r)
+	}
+	if len(actual) != 7 {
+		t.Fatalf("got: %v\nwant: %v", len(actual), 7)
+	}
+
+	first := Record{
+		ID:            store.FindingID("https://github.com/ghost/dummy-repo1", crawler.File{URL: "https://github.com/ghost/dummy-repo1/dummy1.md"}, "detect dummy1-1"),
+		Status:        StatusHit,
+		ScannedAt:     input.FinishedAt,
+		Query:         "test1",
+		RepositoryURL: "https://github.com/ghost/dummy-repo1",
+		Owner:         "ghost",
+		Name:          "dummy-repo1",
+		FileURL:       "https://github.com/ghost/dummy-repo1/dummy1.md",
+		Fragment:      "detect dummy1-1",
+	}
+	if !reflect.DeepEqual(actual[0], first) {
+		t.Errorf("got: %+v\nwant: %+v", actual[0], first)
+	}
+	if actual[6].Status != "resolved" || actual[6].ID != "c95ac5aef243" {
+		t.Errorf("got: %+v", actual[6])
+	}
+}
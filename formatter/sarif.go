@@ -0,0 +1,194 @@
+/**
+ * Copyright (c) 2019-present Future Corporation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package formatter
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/future-architect/code-diaper/store"
+	"io"
+	"strings"
+	"time"
+)
+
+// SARIF 2.1.0 https://docs.oasis-open.org/sarif/sarif/v2.1.0/sarif-v2.1.0.html
+// Only the properties used by code-diaper are defined.
+
+const (
+	sarifVersion = "2.1.0"
+	sarifSchema  = "https://json.schemastore.org/sarif-2.1.0.json"
+)
+
+type sarifLog struct {
+	Schema  string     `json:"$schema"`
+	Version string     `json:"version"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool        sarifTool         `json:"tool"`
+	Invocations []sarifInvocation `json:"invocations"`
+	Results     []sarifResult     `json:"results"`
+}
+
+type sarifTool struct {
+	Driver sarifDriver `json:"driver"`
+}
+
+type sarifDriver struct {
+	Name           string      `json:"name"`
+	InformationURI string      `json:"informationUri"`
+	Rules          []sarifRule `json:"rules"`
+}
+
+type sarifRule struct {
+	ID               string       `json:"id"`
+	ShortDescription sarifMessage `json:"shortDescription"`
+}
+
+type sarifInvocation struct {
+	ExecutionSuccessful bool   `json:"executionSuccessful"`
+	StartTimeUTC        string `json:"startTimeUtc,omitempty"`
+	EndTimeUTC          string `json:"endTimeUtc,omitempty"`
+}
+
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+type sarifResult struct {
+	RuleID     string                 `json:"ruleId"`
+	Level      string                 `json:"level"`
+	Message    sarifMessage           `json:"message"`
+	Locations  []sarifLocation        `json:"locations"`
+	Properties map[string]interface{} `json:"properties,omitempty"`
+}
+
+type sarifLocation struct {
+	PhysicalLocation sarifPhysicalLocation `json:"physicalLocation"`
+}
+
+type sarifPhysicalLocation struct {
+	ArtifactLocation sarifArtifactLocation `json:"artifactLocation"`
+	Region           *sarifRegion          `json:"region,omitempty"`
+}
+
+type sarifArtifactLocation struct {
+	URI string `json:"uri"`
+}
+
+type sarifRegion struct {
+	StartLine int          `json:"startLine"`
+	Snippet   sarifMessage `json:"snippet"`
+}
+
+// FmtSARIF renders one SARIF result per hit file. The query is the rule ID and the fragments are the snippet.
+// Without the matched lines, the snippet is put in the properties instead of the region.
+func FmtSARIF(w io.Writer, r ScanResult) error {
+	run := sarifRun{
+		Tool: sarifTool{
+			Driver: sarifDriver{
+				Name:           "code-diaper",
+				InformationURI: "https://github.com/future-architect/code-diaper",
+				Rules:          []sarifRule{},
+			},
+		},
+		Invocations: []sarifInvocation{{
+			ExecutionSuccessful: true,
+			StartTimeUTC:        sarifTime(r.StartedAt),
+			EndTimeUTC:          sarifTime(r.FinishedAt),
+		}},
+		Results: []sarifResult{},
+	}
+
+	ruleIndex := map[string]bool{}
+	for _, sr := range r.Searches {
+		if !ruleIndex[sr.Query] {
+			ruleIndex[sr.Query] = true
+			run.Tool.Driver.Rules = append(run.Tool.Driver.Rules, sarifRule{
+				ID:               sr.Query,
+				ShortDescription: sarifMessage{Text: fmt.Sprintf("Code that contains %v", sr.Query)},
+			})
+		}
+
+		for _, repo := range sr.Repos {
+			for _, file := range repo.HitFiles {
+				var ids []string
+				for _, fragment := range file.Fragments {
+					ids = append(ids, store.FindingID(repo.URL, file, fragment))
+				}
+				properties := map[string]interface{}{
+					"repository": repo.URL,
+					"findingIds": ids,
+				}
+				if repo.ForkSource != "" {
+					properties["forkSource"] = repo.ForkSource
+				}
+				if !repo.PushedAt.IsZero() {
+					properties["exposure"] = Exposure(repo)
+					properties["stars"] = repo.Stars
+					properties["forks"] = repo.Forks
+					properties["pushedAt"] = repo.PushedAt
+					properties["archived"] = repo.Archived
+				}
+				if len(file.Similar) > 0 {
+					properties["similar"] = file.Similar
+				}
+				if file.Attribution != nil {
+					properties["attribution"] = file.Attribution
+				}
+
+				// a region requires the start position, which is known only for the verified files
+				var region *sarifRegion
+				snippet := strings.Join(file.Fragments, "\n")
+				if len(file.Lines) > 0 {
+					region = &sarifRegion{StartLine: file.Lines[0], Snippet: sarifMessage{Text: snippet}}
+					properties["lines"] = file.Lines
+				} else {
+					properties["snippet"] = snippet
+				}
+
+				run.Results = append(run.Results, sarifResult{
+					RuleID:  sr.Query,
+					Level:   "warning",
+					Message: sarifMessage{Text: fmt.Sprintf("%v is found in %v/%v", sr.Query, repo.Owner, repo.Name)},
+					Locations: []sarifLocation{{
+						PhysicalLocation: sarifPhysicalLocation{
+							ArtifactLocation: sarifArtifactLocation{URI: file.URL},
+							Region:           region,
+						},
+					}},
+					Properties: properties,
+				})
+			}
+		}
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(sarifLog{
+		Schema:  sarifSchema,
+		Version: sarifVersion,
+		Runs:    []sarifRun{run},
+	})
+}
+
+func sarifTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339)
+}
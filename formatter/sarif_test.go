@@ -0,0 +1,97 @@
+/**
+ * Copyright (c) 2019-present Future Corporation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package formatter
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/future-architect/code-diaper/crawler"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFmtSARIF(t *testing.T) {
+	input := ScanResult{
+		StartedAt:  time.Date(2019, 8, 1, 9, 0, 0, 0, time.UTC),
+		FinishedAt: time.Date(2019, 8, 1, 9, 1, 0, 0, time.UTC),
+		Searches:   input1,
+	}
+
+	var buff bytes.Buffer
+	if err := FmtSARIF(&buff, input); err != nil {
+		t.Fatal(err)
+	}
+
+	var actual sarifLog
+	if err := json.Unmarshal(buff.Bytes(), &actual); err != nil {
+		t.Fatal(err)
+	}
+	if actual.Version != "2.1.0" || len(actual.Runs) != 1 {
+		t.Fatalf("got: %+v", actual)
+	}
+
+	run := actual.Runs[0]
+	if len(run.Tool.Driver.Rules) != 2 || run.Tool.Driver.Rules[0].ID != "test1" {
+		t.Errorf("got: %+v", run.Tool.Driver.Rules)
+	}
+	if run.Invocations[0].StartTimeUTC != "2019-08-01T09:00:00Z" {
+		t.Errorf("got: %v\nwant: %v", run.Invocations[0].StartTimeUTC, "2019-08-01T09:00:00Z")
+	}
+	if len(run.Results) != 3 {
+		t.Fatalf("got: %v\nwant: %v", len(run.Results), 3)
+	}
+
+	r := run.Results[1]
+	if r.RuleID != "test1" || r.Locations[0].PhysicalLocation.ArtifactLocation.URI != "https://github.com/ghost/dummy-repo1/dummy2.md" {
+		t.Errorf("got: %+v", r)
+	}
+	if r.Locations[0].PhysicalLocation.Region != nil {
+		t.Errorf("got: %+v\nwant: nil", r.Locations[0].PhysicalLocation.Region)
+	}
+	if snippet := r.Properties["snippet"]; snippet != "detect dummy2-1\ndetect dummy2-2" {
+		t.Errorf("got: %v\nwant: %v", snippet, "detect dummy2-1\ndetect dummy2-2")
+	}
+}
+
+func TestFmtSARIFLines(t *testing.T) {
+	input := ScanResult{Searches: []SearchResult{{
+		Query: "test",
+		Repos: crawler.Repositories{{URL: "https://github.com/ghost/dummy", Owner: "ghost", Name: "dummy",
+			HitFiles: crawler.Files{{URL: "https://github.com/ghost/dummy/a.go", Fragments: []string{"Copyright"}, Verified: true, Lines: []int{3, 10}}}}},
+	}}}
+
+	var buff bytes.Buffer
+	if err := FmtSARIF(&buff, input); err != nil {
+		t.Fatal(err)
+	}
+	var actual sarifLog
+	if err := json.Unmarshal(buff.Bytes(), &actual); err != nil {
+		t.Fatal(err)
+	}
+	region := actual.Runs[0].Results[0].Locations[0].PhysicalLocation.Region
+	if region == nil || region.StartLine != 3 || region.Snippet.Text != "Copyright" {
+		t.Fatalf("got: %+v", region)
+	}
+
+	detail, err := FmtDetail(input.Searches[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(detail, ", 行: 3, 10)") {
+		t.Errorf("got: %v", detail)
+	}
+}
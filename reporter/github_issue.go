@@ -0,0 +1,253 @@
+/**
+ * Copyright (c) 2019-present Future Corporation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package reporter
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"github.com/future-architect/code-diaper/crawler"
+	"github.com/future-architect/code-diaper/formatter"
+	"github.com/future-architect/code-diaper/store"
+	"github.com/google/go-github/github"
+	"regexp"
+	"strings"
+	"text/template"
+)
+
+// IssueLabel is added to the issues created by code-diaper, so that they are found in the next run.
+const IssueLabel = "code-diaper"
+
+// IssueMessage is the body of an issue or a comment for the new findings of a repository.
+const IssueMessage = `<!-- code-diaper-repository: {{ .Repo.URL }} -->
+{{ .Repo.URL }} で検出されました。
+{{- if .Repo.ForkSource }}
+フォーク元: {{ .Repo.ForkSource }}
+{{- end }}
+{{ range $q := .Queries }}
+検索ワード: ` + "`{{ $q }}`" + `
+{{- end }}
+{{ range $file := .Repo.HitFiles }}
+- {{ $file.URL }}
+{{- range $fragment := $file.Fragments }}
+` + "```" + `
+{{ $fragment }}
+` + "```" + `
+{{- end }}
+{{- end }}
+`
+
+var issueMarker = regexp.MustCompile(`<!-- code-diaper-repository: (\S+) -->`)
+
+// GitHubIssueReporter opens an issue per leaking repository in the tracking repository,
+// comments the new findings to the issue, and closes it when all findings of the repository are resolved.
+// It requires the finding store, because new findings and resolved repositories are known only by reconciling with the previous run.
+// Still-present findings are not commented again even if they are reported by ReportAll.
+type GitHubIssueReporter struct {
+	client *github.Client
+	limits *crawler.GitHubRateLimits
+	owner  string
+	repo   string
+}
+
+// NewGitHubIssueReporter returns a reporter for the tracking repository "owner/name".
+func NewGitHubIssueReporter(token, baseURL, uploadURL, trackingRepo string) (*GitHubIssueReporter, error) {
+	split := strings.Split(trackingRepo, "/")
+	if len(split) != 2 || split[0] == "" || split[1] == "" {
+		return nil, fmt.Errorf("tracking repository must be owner/name: %v", trackingRepo)
+	}
+	client, err := crawler.NewGitHubClient(token, baseURL, uploadURL)
+	if err != nil {
+		return nil, err
+	}
+	return &GitHubIssueReporter{
+		client: client,
+		limits: crawler.SharedGitHubRateLimits(client.BaseURL.String(), token),
+		owner:  split[0],
+		repo:   split[1],
+	}, nil
+}
+
+type issueContent struct {
+	Repo    crawler.Repository
+	Queries []string
+}
+
+func (g GitHubIssueReporter) Report(ctx context.Context, result *formatter.ScanResult) error {
+	issues, err := g.listIssues(ctx)
+	if err != nil {
+		return err
+	}
+
+	// a repository found by several queries has one issue
+	var contents []*issueContent
+	index := map[string]*issueContent{}
+	for _, sr := range result.Searches {
+		for _, r := range newFindings(sr) {
+			c, ok := index[r.URL]
+			if !ok {
+				c = &issueContent{Repo: crawler.Repository{URL: r.URL, Owner: r.Owner, Name: r.Name, ForkSource: r.ForkSource}}
+				index[r.URL] = c
+				contents = append(contents, c)
+			}
+			c.Queries = append(c.Queries, sr.Query)
+			for _, f := range r.HitFiles {
+				c.Repo.HitFiles = c.Repo.HitFiles.Merge(f)
+			}
+		}
+	}
+
+	for _, c := range contents {
+		if err := g.open(ctx, issues[c.Repo.URL], c); err != nil {
+			return err
+		}
+	}
+
+	// a repository resolved under several queries is closed once
+	closed := map[string]bool{}
+	for _, sr := range result.Searches {
+		for _, url := range sr.ResolvedRepos {
+			if _, found := index[url]; found || closed[url] {
+				continue
+			}
+			closed[url] = true
+			if err := g.close(ctx, issues[url]); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// newFindings narrows the repositories of the search result down to the new findings.
+func newFindings(sr formatter.SearchResult) crawler.Repositories {
+	ids := map[string]bool{}
+	for _, id := range sr.New {
+		ids[id] = true
+	}
+
+	var result crawler.Repositories
+	for _, r := range sr.Repos {
+		var files crawler.Files
+		for _, f := range r.HitFiles {
+			var fragments []string
+			for _, fragment := range f.Fragments {
+				if ids[store.FindingID(r.URL, f, fragment)] {
+					fragments = append(fragments, fragment)
+				}
+			}
+			if len(fragments) > 0 {
+				f.Fragments = fragments
+				files = append(files, f)
+			}
+		}
+		if len(files) > 0 {
+			r.HitFiles = files
+			result = append(result, r)
+		}
+	}
+	return result
+}
+
+// open creates the issue, or comments to the existing one and reopens it if closed.
+func (g GitHubIssueReporter) open(ctx context.Context, issue *github.Issue, c *issueContent) error {
+	var buff bytes.Buffer
+	if err := template.Must(template.New("issue").Parse(IssueMessage)).Execute(&buff, c); err != nil {
+		return err
+	}
+	body := buff.String()
+
+	if issue == nil {
+		if err := g.limits.Core.Wait(ctx); err != nil {
+			return err
+		}
+		title := fmt.Sprintf("[code-diaper] %v/%v", c.Repo.Owner, c.Repo.Name)
+		_, _, err := g.client.Issues.Create(ctx, g.owner, g.repo, &github.IssueRequest{
+			Title:  &title,
+			Body:   &body,
+			Labels: &[]string{IssueLabel},
+		})
+		return err
+	}
+
+	if issue.GetState() == "closed" {
+		if err := g.limits.Core.Wait(ctx); err != nil {
+			return err
+		}
+		state := "open"
+		if _, _, err := g.client.Issues.Edit(ctx, g.owner, g.repo, issue.GetNumber(), &github.IssueRequest{State: &state}); err != nil {
+			return err
+		}
+	}
+	return g.comment(ctx, issue, body)
+}
+
+// close comments and closes the issue if it is open.
+func (g GitHubIssueReporter) close(ctx context.Context, issue *github.Issue) error {
+	if issue == nil || issue.GetState() != "open" {
+		return nil
+	}
+	if err := g.comment(ctx, issue, "検出されなくなったため、クローズします。"); err != nil {
+		return err
+	}
+	if err := g.limits.Core.Wait(ctx); err != nil {
+		return err
+	}
+	state := "closed"
+	_, _, err := g.client.Issues.Edit(ctx, g.owner, g.repo, issue.GetNumber(), &github.IssueRequest{State: &state})
+	return err
+}
+
+func (g GitHubIssueReporter) comment(ctx context.Context, issue *github.Issue, body string) error {
+	if err := g.limits.Core.Wait(ctx); err != nil {
+		return err
+	}
+	_, _, err := g.client.Issues.CreateComment(ctx, g.owner, g.repo, issue.GetNumber(), &github.IssueComment{Body: &body})
+	return err
+}
+
+// listIssues returns the issues created by code-diaper keyed by the repository URL in the marker.
+func (g GitHubIssueReporter) listIssues(ctx context.Context) (map[string]*github.Issue, error) {
+	result := map[string]*github.Issue{}
+	opt := &github.IssueListByRepoOptions{
+		State:       "all",
+		Labels:      []string{IssueLabel},
+		ListOptions: github.ListOptions{PerPage: crawler.MaxPageSize},
+	}
+	for {
+		if err := g.limits.Core.Wait(ctx); err != nil {
+			return nil, err
+		}
+		issues, resp, err := g.client.Issues.ListByRepo(ctx, g.owner, g.repo, opt)
+		if err != nil {
+			return nil, err
+		}
+		for _, issue := range issues {
+			m := issueMarker.FindStringSubmatch(issue.GetBody())
+			if m == nil {
+				continue
+			}
+			// the newest issue wins if there are duplicates, because the list is sorted by created desc
+			if _, ok := result[m[1]]; !ok {
+				result[m[1]] = issue
+			}
+		}
+		if resp.NextPage == 0 {
+			return result, nil
+		}
+		opt.Page = resp.NextPage
+	}
+}
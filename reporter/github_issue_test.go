@@ -0,0 +1,116 @@
+/**
+ * Copyright (c) 2019-present Future Corporation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package reporter
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/future-architect/code-diaper/condition"
+	"github.com/future-architect/code-diaper/crawler"
+	"github.com/future-architect/code-diaper/formatter"
+	"github.com/future-architect/code-diaper/store"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestGitHubIssueReporter(t *testing.T) {
+	var calls []string
+	var created map[string]interface{}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/v3/repos/future/tracking/issues", func(w http.ResponseWriter, r *http.Request) {
+		calls = append(calls, r.Method+" issues")
+		switch r.Method {
+		case http.MethodGet:
+			if r.URL.Query().Get("labels") != IssueLabel || r.URL.Query().Get("state") != "all" {
+				t.Errorf("unexpected query: %v", r.URL.RawQuery)
+			}
+			fmt.Fprint(w, `[
+				{"number": 1, "state": "open", "body": "<!-- code-diaper-repository: https://github.com/ghost/resolved -->"},
+				{"number": 2, "state": "closed", "body": "<!-- code-diaper-repository: https://github.com/ghost/dummy-repo1 -->"},
+				{"number": 4, "state": "open", "body": "<!-- code-diaper-repository: https://github.com/ghost/present -->"}
+			]`)
+		case http.MethodPost:
+			if err := json.NewDecoder(r.Body).Decode(&created); err != nil {
+				t.Error(err)
+			}
+			fmt.Fprint(w, `{"number": 3}`)
+		}
+	})
+	mux.HandleFunc("/api/v3/repos/future/tracking/issues/", func(w http.ResponseWriter, r *http.Request) {
+		calls = append(calls, r.Method+" "+strings.TrimPrefix(r.URL.Path, "/api/v3/repos/future/tracking/issues/"))
+		fmt.Fprint(w, `{}`)
+	})
+	ts := httptest.NewServer(mux)
+	defer ts.Close()
+
+	r, err := NewGitHubIssueReporter("dummy-token", ts.URL+"/api/v3/", "", "future/tracking")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	result := *dummyResult
+	result.Searches = append([]formatter.SearchResult{}, dummyResult.Searches...)
+	result.Searches[0].Repos = append(result.Searches[0].Repos, result.Searches[0].Repos[0])
+	result.Searches[0].Repos[1].URL = "https://github.com/ghost/new-repo"
+	result.Searches[0].Repos[1].Name = "new-repo"
+	// reported by ReportAll, but found in the previous run too
+	result.Searches[0].Repos = append(result.Searches[0].Repos, crawler.Repository{URL: "https://github.com/ghost/present", Owner: "ghost", Name: "present",
+		HitFiles: crawler.Files{{URL: "https://github.com/ghost/present/a.go", Fragments: []string{"detect present"}}}})
+	for _, repo := range result.Searches[0].Repos[:2] {
+		result.Searches[0].New = append(result.Searches[0].New, store.FindingID(repo.URL, repo.HitFiles[0], repo.HitFiles[0].Fragments[0]))
+	}
+	result.Searches[0].ResolvedRepos = []string{"https://github.com/ghost/resolved"}
+	result.Searches[1].ResolvedRepos = []string{"https://github.com/ghost/resolved"}
+
+	if err := r.Report(context.Background(), &result); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{
+		"GET issues",
+		"PATCH 2",         // reopen dummy-repo1
+		"POST 2/comments", // new findings of dummy-repo1
+		"POST issues",     // new-repo
+		// no comment to the issue of present
+		"POST 1/comments", // resolved
+		"PATCH 1",
+	}
+	if strings.Join(calls, ",") != strings.Join(want, ",") {
+		t.Errorf("got: %v\nwant: %v", calls, want)
+	}
+	if created["title"] != "[code-diaper] ghost/new-repo" {
+		t.Errorf("got: %v", created["title"])
+	}
+	body, _ := created["body"].(string)
+	if !strings.HasPrefix(body, "<!-- code-diaper-repository: https://github.com/ghost/new-repo -->") || !strings.Contains(body, "detect dummy1-1") {
+		t.Errorf("got: %v", body)
+	}
+}
+
+func TestNewGitHubIssueReporterRequiresStore(t *testing.T) {
+	ops := condition.Options{Reporters: []string{NameIssue}, GitHubToken: "dummy-token", GitHubIssueRepo: "future/tracking"}
+	if _, err := New(ops); err == nil {
+		t.Errorf("got: nil\nwant: error")
+	}
+	ops.StorePath = "./findings.json"
+	if _, err := New(ops); err != nil {
+		t.Errorf("got: %v\nwant: nil", err)
+	}
+}
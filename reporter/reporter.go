@@ -0,0 +1,125 @@
+/**
+ * Copyright (c) 2019-present Future Corporation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package reporter
+
+import (
+	"context"
+	"fmt"
+	"github.com/future-architect/code-diaper/condition"
+	"github.com/future-architect/code-diaper/formatter"
+	"strings"
+)
+
+const (
+	NameSlack   = "slack"
+	NameTeams   = "teams"
+	NameSMTP    = "smtp"
+	NameWebhook = "webhook"
+	NameIssue   = "github_issue"
+)
+
+// Reporter notifies the scan result.
+type Reporter interface {
+	Report(ctx context.Context, result *formatter.ScanResult) error
+}
+
+// New returns the reporters enabled by ops.Reporters. A new backend is added here, not to the entry points.
+func New(ops condition.Options) ([]Reporter, error) {
+	var result []Reporter
+	for _, name := range ops.Reporters {
+		switch name {
+		case NameSlack:
+			if ops.SlackToken == "" || ops.SlackChannel == "" {
+				return nil, fmt.Errorf("required parameter for %v reporter: SlackToken and SlackChannel", name)
+			}
+			slack := NewSlackReporter(ops.SlackToken, ops.SlackChannel)
+			slack.UploadHTML = ops.SlackUploadHTML
+			result = append(result, slack)
+		case NameTeams:
+			if ops.TeamsWebhookURL == "" {
+				return nil, fmt.Errorf("required parameter for %v reporter: TeamsWebhookURL", name)
+			}
+			result = append(result, NewTeamsReporter(ops.TeamsWebhookURL))
+		case NameSMTP:
+			if ops.SMTPAddr == "" || ops.SMTPFrom == "" {
+				return nil, fmt.Errorf("required parameter for %v reporter: SMTPAddr and SMTPFrom", name)
+			}
+			if !hasRecipient(ops) {
+				return nil, fmt.Errorf("required parameter for %v reporter: SMTPTo or mail_to of a search", name)
+			}
+			result = append(result, NewSMTPReporter(SMTPConfig{
+				Addr:     ops.SMTPAddr,
+				Username: ops.SMTPUsername,
+				Password: ops.SMTPPassword,
+				From:     ops.SMTPFrom,
+				To:       ops.SMTPTo,
+				StartTLS: ops.SMTPStartTLS,
+			}))
+		case NameWebhook:
+			if ops.WebhookURL == "" {
+				return nil, fmt.Errorf("required parameter for %v reporter: WebhookURL", name)
+			}
+			webhook, err := NewWebhookReporter(ops.WebhookURL, ops.WebhookSecret, ops.WebhookTemplate)
+			if err != nil {
+				return nil, err
+			}
+			result = append(result, webhook)
+		case NameIssue:
+			if ops.GitHubToken == "" || ops.GitHubIssueRepo == "" {
+				return nil, fmt.Errorf("required parameter for %v reporter: GitHubToken and GitHubIssueRepo", name)
+			}
+			if ops.StorePath == "" {
+				// without the store, every run would comment all findings again
+				return nil, fmt.Errorf("required parameter for %v reporter: StorePath", name)
+			}
+			issue, err := NewGitHubIssueReporter(ops.GitHubToken, ops.GitHubBaseURL, ops.GitHubUploadURL, ops.GitHubIssueRepo)
+			if err != nil {
+				return nil, err
+			}
+			result = append(result, issue)
+		default:
+			return nil, fmt.Errorf("unknown reporter: %v", name)
+		}
+	}
+	return result, nil
+}
+
+// hasRecipient reports whether the email digest has at least one recipient.
+func hasRecipient(ops condition.Options) bool {
+	if len(ops.SMTPTo) > 0 {
+		return true
+	}
+	for _, s := range ops.SearchList {
+		if len(s.MailTo) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// ReportAll runs all reporters. A failure of one reporter does not stop the others.
+func ReportAll(ctx context.Context, reporters []Reporter, result *formatter.ScanResult) error {
+	var msgs []string
+	for _, r := range reporters {
+		if err := r.Report(ctx, result); err != nil {
+			msgs = append(msgs, err.Error())
+		}
+	}
+	if len(msgs) > 0 {
+		return fmt.Errorf("report failed: %v", strings.Join(msgs, "; "))
+	}
+	return nil
+}
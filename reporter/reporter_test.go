@@ -0,0 +1,74 @@
+/**
+ * Copyright (c) 2019-present Future Corporation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package reporter
+
+import (
+	"context"
+	"errors"
+	"github.com/future-architect/code-diaper/condition"
+	"github.com/future-architect/code-diaper/formatter"
+	"testing"
+)
+
+type fakeReporter struct {
+	called int
+	err    error
+}
+
+func (r *fakeReporter) Report(ctx context.Context, result *formatter.ScanResult) error {
+	r.called++
+	return r.err
+}
+
+func TestReportAll(t *testing.T) {
+	failed := &fakeReporter{err: errors.New("dummy error")}
+	succeeded := &fakeReporter{}
+
+	err := ReportAll(context.Background(), []Reporter{failed, succeeded}, &formatter.ScanResult{})
+	if err == nil {
+		t.Errorf("got: nil\nwant: error")
+	}
+	if failed.called != 1 || succeeded.called != 1 {
+		t.Errorf("got: %v, %v\nwant: 1, 1", failed.called, succeeded.called)
+	}
+}
+
+func TestNew(t *testing.T) {
+	reporters, err := New(condition.Options{Reporters: condition.List{NameSlack}, SlackToken: "dummy-token", SlackChannel: "dummy"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(reporters) != 1 {
+		t.Errorf("got: %v\nwant: %v", len(reporters), 1)
+	}
+
+	if _, err := New(condition.Options{Reporters: condition.List{NameSlack}}); err == nil {
+		t.Errorf("got: nil\nwant: error for missing token")
+	}
+
+	smtp := condition.Options{Reporters: condition.List{NameSMTP}, SMTPAddr: "localhost:25", SMTPFrom: "diaper@example.com"}
+	if _, err := New(smtp); err == nil {
+		t.Errorf("got: nil\nwant: error for no recipients")
+	}
+	smtp.SearchList = []condition.Search{{MailTo: condition.List{"team@example.com"}}}
+	if _, err := New(smtp); err != nil {
+		t.Errorf("got: %v\nwant: nil", err)
+	}
+
+	if _, err := New(condition.Options{Reporters: condition.List{"unknown"}}); err == nil {
+		t.Errorf("got: nil\nwant: error for unknown reporter")
+	}
+}
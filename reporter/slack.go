@@ -16,30 +16,142 @@
 package reporter
 
 import (
+	"bytes"
 	"context"
+	"fmt"
+	"github.com/future-architect/code-diaper/formatter"
 	"github.com/nlopes/slack"
+	"log"
+	"strings"
+	"time"
 )
 
 type SlackReporter struct {
-	api     *slack.Client
-	channel string
+	api        *slack.Client
+	channel    string
+	UploadHTML bool // attach the HTML report to the thread
+	maxRetries int  // retries of a rate limited request
 }
 
 func NewSlackReporter(token, channel string) *SlackReporter {
 	return &SlackReporter{
-		api:     slack.New(token),
-		channel: channel,
+		api:        slack.New(token),
+		channel:    channel,
+		maxRetries: 3,
 	}
 }
 
+// Report posts the summary and the details of each search to its thread.
+// A detail is split into messages on repository boundaries. A failed message does not stop the remaining details.
+func (s SlackReporter) Report(ctx context.Context, result *formatter.ScanResult) error {
+	summary, err := formatter.FmtTop(result.Searches)
+	if err != nil {
+		return err
+	}
+
+	ts, err := s.Post(ctx, summary)
+	if err != nil {
+		return err
+	}
+
+	var msgs []string
+	for _, sr := range result.Searches {
+		for _, part := range SplitDetail(sr) {
+			if err := s.postDetail(ctx, ts, part); err != nil {
+				log.Printf("slack post failed: %v: %v\n", sr.Query, err)
+				msgs = append(msgs, err.Error())
+			}
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+		}
+	}
+
+	if s.UploadHTML {
+		var buff bytes.Buffer
+		if err := formatter.FmtHTML(&buff, *result); err != nil {
+			return err
+		}
+		if err := s.Upload(ctx, ts, "code-diaper.html", buff.Bytes()); err != nil {
+			msgs = append(msgs, err.Error())
+		}
+	}
+
+	if len(msgs) > 0 {
+		return fmt.Errorf("slack: %d messages failed: %v", len(msgs), strings.Join(msgs, "; "))
+	}
+	return nil
+}
+
+// postDetail posts a part of the search made by SplitDetail.
+func (s SlackReporter) postDetail(ctx context.Context, ts string, sr formatter.SearchResult) error {
+	// the text is the fallback for notifications
+	detail, err := formatter.FmtDetail(sr)
+	if err != nil {
+		return err
+	}
+	detail = truncate(detail, maxSlackText)
+	if len(sr.Repos) == 0 {
+		return s.PostThread(ctx, ts, detail)
+	}
+	return s.PostBlocks(ctx, ts, detail, DetailBlocks(sr))
+}
+
 // Post is send func for slack.
 func (s SlackReporter) Post(ctx context.Context, msg string) (string, error) {
-	_, ts, err := s.api.PostMessageContext(ctx, s.channel, slack.MsgOptionText(msg, false))
-	return ts, err
+	return s.postMessage(ctx, slack.MsgOptionText(msg, false))
 }
 
 // PostThread is send func for slack thread. timestamp is parent message timestamp.
 func (s SlackReporter) PostThread(ctx context.Context, timeStamp, msg string) error {
-	_, _, err := s.api.PostMessageContext(ctx, s.channel, slack.MsgOptionText(msg, false), slack.MsgOptionTS(timeStamp))
+	_, err := s.postMessage(ctx, slack.MsgOptionText(msg, false), slack.MsgOptionTS(timeStamp))
 	return err
 }
+
+// PostBlocks is send func for slack thread with Block Kit. msg is shown in notifications.
+func (s SlackReporter) PostBlocks(ctx context.Context, timeStamp, msg string, blocks []slack.Block) error {
+	_, err := s.postMessage(ctx, slack.MsgOptionText(msg, false), slack.MsgOptionBlocks(blocks...), slack.MsgOptionTS(timeStamp))
+	return err
+}
+
+// Upload attaches the file to the thread. timestamp is parent message timestamp.
+func (s SlackReporter) Upload(ctx context.Context, timeStamp, filename string, content []byte) error {
+	return s.retry(ctx, func() error {
+		_, err := s.api.UploadFileContext(ctx, slack.FileUploadParameters{
+			Reader:          bytes.NewReader(content),
+			Filename:        filename,
+			Title:           filename,
+			Channels:        []string{s.channel},
+			ThreadTimestamp: timeStamp,
+		})
+		return err
+	})
+}
+
+func (s SlackReporter) postMessage(ctx context.Context, options ...slack.MsgOption) (string, error) {
+	var ts string
+	err := s.retry(ctx, func() error {
+		var err error
+		_, ts, err = s.api.PostMessageContext(ctx, s.channel, options...)
+		return err
+	})
+	return ts, err
+}
+
+// retry calls f again after Retry-After of Slack while it is rate limited.
+func (s SlackReporter) retry(ctx context.Context, f func() error) error {
+	for i := 0; ; i++ {
+		err := f()
+		rateLimited, ok := err.(*slack.RateLimitedError)
+		if !ok || i >= s.maxRetries {
+			return err
+		}
+		log.Printf("slack rate limited. retry after %v\n", rateLimited.RetryAfter)
+
+		select {
+		case <-time.After(rateLimited.RetryAfter):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
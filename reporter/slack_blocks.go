@@ -0,0 +1,222 @@
+/**
+ * Copyright (c) 2019-present Future Corporation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package reporter
+
+import (
+	"fmt"
+	"github.com/future-architect/code-diaper/crawler"
+	"github.com/future-architect/code-diaper/formatter"
+	"github.com/future-architect/code-diaper/store"
+	"github.com/nlopes/slack"
+	"strings"
+)
+
+const (
+	// MaxSlackBlocks is the number of blocks Slack accepts in one message.
+	MaxSlackBlocks = 50
+
+	// maxSlackFragment is the length of a fragment shown in a context block.
+	maxSlackFragment = 300
+
+	// maxSlackSection is the length limit of the text of a section block.
+	maxSlackSection = 3000
+
+	// maxSlackText is the length of the text of a message. Slack truncates a longer text.
+	maxSlackText = 4000
+
+	// triageActionPrefix + triage state is the action ID of the triage buttons. The value is the finding ID.
+	triageActionPrefix = "triage:"
+)
+
+// triageButtons are the buttons attached to each fragment.
+var triageButtons = []struct {
+	state store.TriageState
+	label string
+	style slack.Style
+}{
+	{store.TriageFalsePositive, "False positive", slack.StyleDefault},
+	{store.TriageAcknowledged, "Acknowledge", slack.StylePrimary},
+}
+
+// DetailBlocks renders the search as Block Kit: a section per repository and file, a context block per fragment and triage buttons.
+// Blocks over MaxSlackBlocks are omitted with a note.
+func DetailBlocks(sr formatter.SearchResult) []slack.Block {
+	title := sr.Query + "の詳細結果"
+	if sr.Host != "" {
+		title = "[" + sr.Host + "] " + title
+	}
+	blocks := []slack.Block{markdownSection(fmt.Sprintf("*%v*", escapeSlack(title)))}
+
+	for i, repo := range sr.Repos {
+		repoBlocks := RepositoryBlocks(repo)
+		if len(blocks)+len(repoBlocks)+1 > MaxSlackBlocks {
+			if i == 0 {
+				// a repository too large for one message is cut after the buttons of a fragment
+				n := MaxSlackBlocks - 1 - len(blocks)
+				for n > 0 && !isActionBlock(repoBlocks[n-1]) {
+					n--
+				}
+				blocks = append(blocks, repoBlocks[:n]...)
+				blocks = append(blocks, contextText("...以降省略"))
+				break
+			}
+			blocks = append(blocks, contextText(fmt.Sprintf("...他%d件", len(sr.Repos)-i)))
+			break
+		}
+		blocks = append(blocks, repoBlocks...)
+	}
+
+	if urls := sr.Resolved.FileURLs(); len(urls) > 0 {
+		var lines []string
+		for _, url := range urls {
+			lines = append(lines, fmt.Sprintf("<%v>", url))
+		}
+		if len(blocks) >= MaxSlackBlocks {
+			blocks = blocks[:MaxSlackBlocks-1]
+		}
+		blocks = append(blocks, markdownSection(truncate("*解消済み*\n"+strings.Join(lines, "\n"), maxSlackSection)))
+	}
+	return blocks
+}
+
+// SplitDetail splits the search on repository boundaries so that each part fits in one message.
+// Resolved files are attached to the last part, or to an additional part without repositories if they do not fit.
+func SplitDetail(sr formatter.SearchResult) []formatter.SearchResult {
+	var result []formatter.SearchResult
+	part := sr
+	part.Repos = nil
+	part.Resolved = nil
+	for _, repo := range sr.Repos {
+		next := part
+		next.Repos = append(part.Repos[:len(part.Repos):len(part.Repos)], repo)
+		if len(part.Repos) > 0 && !fitsMessage(next) {
+			result = append(result, part)
+			next.Repos = crawler.Repositories{repo}
+		}
+		part = next
+	}
+	if len(sr.Resolved) == 0 {
+		if len(part.Repos) > 0 {
+			result = append(result, part)
+		}
+		return result
+	}
+
+	withResolved := part
+	withResolved.Resolved = sr.Resolved
+	if len(part.Repos) == 0 || fitsMessage(withResolved) {
+		return append(result, withResolved)
+	}
+	resolved := part
+	resolved.Repos = nil
+	resolved.Resolved = sr.Resolved
+	return append(result, part, resolved)
+}
+
+// fitsMessage reports whether the blocks and the fallback text of the search are within the limits of a message.
+func fitsMessage(sr formatter.SearchResult) bool {
+	count := 1
+	for _, repo := range sr.Repos {
+		count += len(RepositoryBlocks(repo))
+	}
+	if len(sr.Resolved) > 0 {
+		count++
+	}
+	if count > MaxSlackBlocks {
+		return false
+	}
+	detail, err := formatter.FmtDetail(sr)
+	if err != nil {
+		// the error is reported when the part is posted
+		return true
+	}
+	return len([]rune(detail)) <= maxSlackText
+}
+
+// RepositoryBlocks renders one repository.
+func RepositoryBlocks(repo crawler.Repository) []slack.Block {
+	header := fmt.Sprintf("*<%v|%v/%v>*", repo.URL, escapeSlack(repo.Owner), escapeSlack(repo.Name))
+	if repo.ForkSource != "" {
+		header += fmt.Sprintf(" (フォーク元: %v)", escapeSlack(repo.ForkSource))
+	}
+	if note := formatter.ExposureNote(repo); note != "" {
+		header += "\n" + escapeSlack(note)
+	}
+	blocks := []slack.Block{slack.NewDividerBlock(), markdownSection(header)}
+
+	for _, file := range repo.HitFiles {
+		text := fmt.Sprintf("<%v>", file.URL)
+		for _, similar := range file.Similar {
+			text += fmt.Sprintf("\n類似: %v (%.0f%%)", escapeSlack(similar.Path), similar.Score*100)
+		}
+		if a := file.Attribution; a != nil {
+			if a.LastCommit != nil {
+				text += "\n最終コミット: " + escapeSlack(formatter.FmtCommit(*a.LastCommit))
+			}
+			if a.Introduced != nil {
+				text += "\n追加コミット: " + escapeSlack(formatter.FmtCommit(*a.Introduced))
+			}
+		}
+		blocks = append(blocks, markdownSection(text))
+		for _, fragment := range file.Fragments {
+			id := store.FindingID(repo.URL, file, fragment)
+			blocks = append(blocks,
+				slack.NewContextBlock("", slack.NewTextBlockObject(slack.MarkdownType, fmt.Sprintf("```%v```\nID: %v", escapeSlack(truncate(fragment, maxSlackFragment)), id), false, false)),
+				triageActions(id),
+			)
+		}
+	}
+	return blocks
+}
+
+func triageActions(id string) *slack.ActionBlock {
+	var elements []slack.BlockElement
+	for _, b := range triageButtons {
+		button := slack.NewButtonBlockElement(triageActionPrefix+string(b.state), id, slack.NewTextBlockObject(slack.PlainTextType, b.label, false, false))
+		if b.style != slack.StyleDefault {
+			button.WithStyle(b.style)
+		}
+		elements = append(elements, button)
+	}
+	// block ID must be unique in a message
+	return slack.NewActionBlock("triage-"+id, elements...)
+}
+
+func isActionBlock(b slack.Block) bool {
+	_, ok := b.(*slack.ActionBlock)
+	return ok
+}
+
+func contextText(text string) *slack.ContextBlock {
+	return slack.NewContextBlock("", slack.NewTextBlockObject(slack.MarkdownType, text, false, false))
+}
+
+func markdownSection(text string) *slack.SectionBlock {
+	return slack.NewSectionBlock(slack.NewTextBlockObject(slack.MarkdownType, text, false, false), nil, nil)
+}
+
+// escapeSlack escapes the control characters of Slack mrkdwn.
+func escapeSlack(s string) string {
+	return strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;").Replace(s)
+}
+
+func truncate(s string, n int) string {
+	r := []rune(s)
+	if len(r) <= n {
+		return s
+	}
+	return string(r[:n]) + "…"
+}
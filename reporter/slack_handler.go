@@ -0,0 +1,121 @@
+/**
+ * Copyright (c) 2019-present Future Corporation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package reporter
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/future-architect/code-diaper/store"
+	"github.com/nlopes/slack"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// maxInteractionBody is the size limit of the request body from Slack.
+const maxInteractionBody = 1 << 20
+
+// SlackTriageHandler receives the triage buttons of Slack (Interactivity Request URL)
+// and records the decision in the finding store, so that the next run respects it.
+type SlackTriageHandler struct {
+	signingSecret string
+	store         store.Store
+
+	mu sync.Mutex // serializes Load and Save of the store
+}
+
+func NewSlackTriageHandler(signingSecret string, st store.Store) *SlackTriageHandler {
+	return &SlackTriageHandler{
+		signingSecret: signingSecret,
+		store:         st,
+	}
+}
+
+func (h *SlackTriageHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	body, err := ioutil.ReadAll(http.MaxBytesReader(w, r.Body, maxInteractionBody))
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	verifier, err := slack.NewSecretsVerifier(r.Header, h.signingSecret)
+	if err != nil {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+	if _, err := verifier.Write(body); err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	if err := verifier.Ensure(); err != nil {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	form, err := url.ParseQuery(string(body))
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	var callback slack.InteractionCallback
+	if err := json.Unmarshal([]byte(form.Get("payload")), &callback); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	for _, action := range callback.ActionCallback.BlockActions {
+		if !strings.HasPrefix(action.ActionID, triageActionPrefix) {
+			continue
+		}
+		state, err := store.ParseTriageState(strings.TrimPrefix(action.ActionID, triageActionPrefix))
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		note := fmt.Sprintf("by %v via Slack", callback.User.Name)
+		if err := h.triage(r, action.Value, state, note); err != nil {
+			log.Printf("triage %v: %v", action.Value, err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		log.Printf("%v is marked as %v %v", action.Value, state, note)
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+func (h *SlackTriageHandler) triage(r *http.Request, id string, state store.TriageState, note string) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	findings, err := h.store.Load(r.Context())
+	if err != nil {
+		return err
+	}
+	findings, err = findings.SetTriage(id, state, note, time.Now())
+	if err != nil {
+		return err
+	}
+	return h.store.Save(r.Context(), findings)
+}
@@ -0,0 +1,231 @@
+/**
+ * Copyright (c) 2019-present Future Corporation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package reporter
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"github.com/future-architect/code-diaper/crawler"
+	"github.com/future-architect/code-diaper/formatter"
+	"github.com/future-architect/code-diaper/store"
+	"github.com/nlopes/slack"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDetailBlocks(t *testing.T) {
+	blocks := DetailBlocks(dummyResult.Searches[0])
+
+	// title, divider, repository, file, fragment, buttons
+	if len(blocks) != 6 {
+		t.Fatalf("got: %v\nwant: %v", len(blocks), 6)
+	}
+	actions, ok := blocks[5].(*slack.ActionBlock)
+	if !ok {
+		t.Fatalf("got: %T\nwant: *slack.ActionBlock", blocks[5])
+	}
+	id := store.FindingID("https://github.com/ghost/dummy-repo1", crawler.File{URL: "https://github.com/ghost/dummy-repo1/dummy1.md"}, "detect dummy1-1")
+	button := actions.Elements.ElementSet[0].(*slack.ButtonBlockElement)
+	if button.ActionID != "triage:false_positive" || button.Value != id {
+		t.Errorf("got: %v %v\nwant: %v %v", button.ActionID, button.Value, "triage:false_positive", id)
+	}
+}
+
+func TestDetailBlocksLimit(t *testing.T) {
+	blocks := DetailBlocks(formatter.SearchResult{Query: "test", Repos: manyRepos(20)})
+
+	if len(blocks) > MaxSlackBlocks {
+		t.Errorf("got: %v\nwant: <= %v", len(blocks), MaxSlackBlocks)
+	}
+	b, err := json.Marshal(blocks[len(blocks)-1])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(b), "...他11件") {
+		t.Errorf("got: %s", b)
+	}
+}
+
+func TestSlackReporter(t *testing.T) {
+	var posts []url.Values
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseForm(); err != nil {
+			t.Error(err)
+		}
+		posts = append(posts, r.PostForm)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"ok": true, "channel": "C1", "ts": "1.0"}`))
+	}))
+	defer ts.Close()
+
+	reporter := &SlackReporter{api: slack.New("token", slack.OptionAPIURL(ts.URL+"/")), channel: "C1"}
+	if err := reporter.Report(context.Background(), dummyResult); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(posts) != 2 {
+		t.Fatalf("got: %v\nwant: %v", len(posts), 2)
+	}
+	if posts[1].Get("thread_ts") != "1.0" || !strings.Contains(posts[1].Get("blocks"), "triage:acknowledged") {
+		t.Errorf("got: %v", posts[1])
+	}
+}
+
+func manyRepos(n int) crawler.Repositories {
+	var repos crawler.Repositories
+	for i := 0; i < n; i++ {
+		repos = append(repos, crawler.Repository{URL: fmt.Sprintf("https://github.com/ghost/repo%d", i), Owner: "ghost", Name: fmt.Sprintf("repo%d", i),
+			HitFiles: crawler.Files{{URL: fmt.Sprintf("https://github.com/ghost/repo%d/a.go", i), Fragments: []string{"Copyright 2019 <Example>"}}}})
+	}
+	return repos
+}
+
+func TestSplitDetail(t *testing.T) {
+	sr := formatter.SearchResult{Query: "test", Repos: manyRepos(20)}
+	parts := SplitDetail(sr)
+
+	if len(parts) != 3 {
+		t.Fatalf("got: %v\nwant: %v", len(parts), 3)
+	}
+	var count int
+	for _, part := range parts {
+		if blocks := DetailBlocks(part); len(blocks) > MaxSlackBlocks {
+			t.Errorf("got: %v\nwant: <= %v", len(blocks), MaxSlackBlocks)
+		}
+		count += len(part.Repos)
+	}
+	if count != 20 || parts[1].Repos[0].Name != "repo9" {
+		t.Errorf("got: %v, %v", count, parts[1].Repos[0].Name)
+	}
+
+	if actual := SplitDetail(formatter.SearchResult{Query: "test"}); len(actual) != 0 {
+		t.Errorf("got: %v\nwant: []", actual)
+	}
+}
+
+func TestSlackReporterRateLimited(t *testing.T) {
+	var calls, posts int
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if calls == 2 {
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		posts++
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"ok": true, "channel": "C1", "ts": "1.0"}`))
+	}))
+	defer ts.Close()
+
+	reporter := &SlackReporter{api: slack.New("token", slack.OptionAPIURL(ts.URL+"/")), channel: "C1", maxRetries: 3}
+	if err := reporter.Report(context.Background(), dummyResult); err != nil {
+		t.Fatal(err)
+	}
+	if calls != 3 || posts != 2 {
+		t.Errorf("got: %v, %v\nwant: %v, %v", calls, posts, 3, 2)
+	}
+}
+
+func TestSlackReporterContinue(t *testing.T) {
+	var posts int
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		posts++
+		w.Header().Set("Content-Type", "application/json")
+		if posts == 2 {
+			w.Write([]byte(`{"ok": false, "error": "invalid_blocks"}`))
+			return
+		}
+		w.Write([]byte(`{"ok": true, "channel": "C1", "ts": "1.0"}`))
+	}))
+	defer ts.Close()
+
+	result := &formatter.ScanResult{Searches: []formatter.SearchResult{
+		{Query: "test1", Repos: manyRepos(1)},
+		{Query: "test2", Repos: manyRepos(1)},
+	}}
+	reporter := &SlackReporter{api: slack.New("token", slack.OptionAPIURL(ts.URL+"/")), channel: "C1"}
+	err := reporter.Report(context.Background(), result)
+	if err == nil || !strings.Contains(err.Error(), "invalid_blocks") {
+		t.Errorf("got: %v\nwant: invalid_blocks", err)
+	}
+	if posts != 3 {
+		t.Errorf("got: %v\nwant: %v", posts, 3)
+	}
+}
+
+func TestSlackTriageHandler(t *testing.T) {
+	dir, err := ioutil.TempDir("", "codediaper")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	ctx := context.Background()
+	st := store.NewFileStore(filepath.Join(dir, "findings.json"))
+	findings := store.NewFindings("test1", dummyResult.Searches[0].Repos, time.Now())
+	if err := st.Save(ctx, findings); err != nil {
+		t.Fatal(err)
+	}
+
+	payload := fmt.Sprintf(`{"type": "block_actions", "user": {"name": "ghost"}, "actions": [{"action_id": "triage:false_positive", "block_id": "triage-%v", "value": "%v"}]}`, findings[0].ID, findings[0].ID)
+	body := url.Values{"payload": {payload}}.Encode()
+	handler := NewSlackTriageHandler("secret", st)
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, signedRequest("wrong", body))
+	if rec.Code != http.StatusUnauthorized {
+		t.Errorf("got: %v\nwant: %v", rec.Code, http.StatusUnauthorized)
+	}
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, signedRequest("secret", body))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("got: %v\nwant: %v", rec.Code, http.StatusOK)
+	}
+
+	actual, err := st.Load(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if actual[0].Triage == nil || actual[0].Triage.State != store.TriageFalsePositive || actual[0].Triage.Note != "by ghost via Slack" {
+		t.Errorf("got: %+v", actual[0].Triage)
+	}
+}
+
+func signedRequest(secret, body string) *http.Request {
+	ts := strconv.FormatInt(time.Now().Unix(), 10)
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte("v0:" + ts + ":" + body))
+
+	r := httptest.NewRequest(http.MethodPost, "/slack/interactions", strings.NewReader(body))
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	r.Header.Set("X-Slack-Request-Timestamp", ts)
+	r.Header.Set("X-Slack-Signature", "v0="+hex.EncodeToString(mac.Sum(nil)))
+	return r
+}
@@ -0,0 +1,216 @@
+/**
+ * Copyright (c) 2019-present Future Corporation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package reporter
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"fmt"
+	"github.com/future-architect/code-diaper/formatter"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net"
+	"net/smtp"
+	"net/textproto"
+	"sort"
+	"strings"
+	"time"
+)
+
+type SMTPConfig struct {
+	Addr     string // host:port
+	Username string // AUTH PLAIN is used if set
+	Password string
+	From     string
+	To       []string // receive all searches
+	StartTLS bool     // fail if the server does not support STARTTLS
+}
+
+// SMTPReporter sends a digest email. Each recipient receives one email that contains the searches addressed to it.
+type SMTPReporter struct {
+	config    SMTPConfig
+	tlsConfig *tls.Config
+}
+
+func NewSMTPReporter(config SMTPConfig) *SMTPReporter {
+	host, _, _ := net.SplitHostPort(config.Addr)
+	return &SMTPReporter{
+		config:    config,
+		tlsConfig: &tls.Config{ServerName: host},
+	}
+}
+
+// Report sends the digest to the recipients of Options and of each search.
+func (s SMTPReporter) Report(ctx context.Context, result *formatter.ScanResult) error {
+	for _, to := range s.recipients(result) {
+		digest := *result
+		digest.Searches = nil
+		for _, sr := range result.Searches {
+			if contains(s.config.To, to) || contains(sr.MailTo, to) {
+				digest.Searches = append(digest.Searches, sr)
+			}
+		}
+
+		msg, err := s.message(to, digest)
+		if err != nil {
+			return err
+		}
+		if err := s.send(ctx, to, msg); err != nil {
+			return fmt.Errorf("send email to %v: %v", to, err)
+		}
+	}
+	return nil
+}
+
+func (s SMTPReporter) recipients(result *formatter.ScanResult) []string {
+	set := map[string]bool{}
+	for _, v := range s.config.To {
+		set[v] = true
+	}
+	for _, sr := range result.Searches {
+		for _, v := range sr.MailTo {
+			set[v] = true
+		}
+	}
+	var list []string
+	for v := range set {
+		list = append(list, v)
+	}
+	sort.Strings(list)
+	return list
+}
+
+// message builds a multipart/alternative email of the text templates and the HTML report.
+func (s SMTPReporter) message(to string, result formatter.ScanResult) ([]byte, error) {
+	summary, details, err := formatter.FmtText(result)
+	if err != nil {
+		return nil, err
+	}
+	var html bytes.Buffer
+	if err := formatter.FmtHTML(&html, result); err != nil {
+		return nil, err
+	}
+
+	hitCount := 0
+	for _, sr := range result.Searches {
+		hitCount += sr.HitCount
+	}
+	subject := fmt.Sprintf("[code-diaper] 検索結果 %v: %d件", result.FinishedAt.Format("2006-01-02"), hitCount)
+
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+
+	var buff bytes.Buffer
+	fmt.Fprintf(&buff, "From: %v\r\n", s.config.From)
+	fmt.Fprintf(&buff, "To: %v\r\n", to)
+	fmt.Fprintf(&buff, "Subject: %v\r\n", mime.BEncoding.Encode("UTF-8", subject))
+	fmt.Fprintf(&buff, "Date: %v\r\n", time.Now().Format(time.RFC1123Z))
+	fmt.Fprintf(&buff, "MIME-Version: 1.0\r\n")
+	fmt.Fprintf(&buff, "Content-Type: multipart/alternative; boundary=%v\r\n\r\n", mw.Boundary())
+
+	text := summary + "\n\n" + strings.Join(details, "\n\n") + "\n"
+	for _, part := range []struct {
+		contentType string
+		content     string
+	}{
+		{"text/plain; charset=UTF-8", text},
+		{"text/html; charset=UTF-8", html.String()},
+	} {
+		w, err := mw.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {part.contentType},
+			"Content-Transfer-Encoding": {"quoted-printable"},
+		})
+		if err != nil {
+			return nil, err
+		}
+		qw := quotedprintable.NewWriter(w)
+		if _, err := qw.Write([]byte(part.content)); err != nil {
+			return nil, err
+		}
+		if err := qw.Close(); err != nil {
+			return nil, err
+		}
+	}
+	if err := mw.Close(); err != nil {
+		return nil, err
+	}
+
+	buff.Write(body.Bytes())
+	return buff.Bytes(), nil
+}
+
+func (s SMTPReporter) send(ctx context.Context, to string, msg []byte) error {
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "tcp", s.config.Addr)
+	if err != nil {
+		return err
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+
+	host, _, _ := net.SplitHostPort(s.config.Addr)
+	c, err := smtp.NewClient(conn, host)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	defer c.Close()
+
+	if s.config.StartTLS {
+		if ok, _ := c.Extension("STARTTLS"); !ok {
+			return fmt.Errorf("%v does not support STARTTLS", s.config.Addr)
+		}
+		if err := c.StartTLS(s.tlsConfig); err != nil {
+			return err
+		}
+	}
+	if s.config.Username != "" {
+		// PlainAuth refuses to send the password without TLS except for localhost
+		if err := c.Auth(smtp.PlainAuth("", s.config.Username, s.config.Password, host)); err != nil {
+			return err
+		}
+	}
+
+	if err := c.Mail(s.config.From); err != nil {
+		return err
+	}
+	if err := c.Rcpt(to); err != nil {
+		return err
+	}
+	w, err := c.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(msg); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return c.Quit()
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,197 @@
+/**
+ * Copyright (c) 2019-present Future Corporation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package reporter
+
+import (
+	"bufio"
+	"context"
+	"encoding/base64"
+	"github.com/future-architect/code-diaper/formatter"
+	"io/ioutil"
+	"mime"
+	"mime/multipart"
+	"net"
+	"net/mail"
+	"strings"
+	"sync"
+	"testing"
+)
+
+type receivedMail struct {
+	auth string
+	from string
+	to   []string
+	data string
+}
+
+// smtpStub is a minimal in-process SMTP server that records the received emails.
+type smtpStub struct {
+	ln    net.Listener
+	mu    sync.Mutex
+	mails []receivedMail
+}
+
+func newSMTPStub(t *testing.T) *smtpStub {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &smtpStub{ln: ln}
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go s.serve(conn)
+		}
+	}()
+	return s
+}
+
+func (s *smtpStub) serve(conn net.Conn) {
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	reply := func(line string) { conn.Write([]byte(line + "\r\n")) }
+
+	var m receivedMail
+	reply("220 localhost ESMTP stub")
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+		line = strings.TrimRight(line, "\r\n")
+		cmd := strings.ToUpper(strings.SplitN(line, " ", 2)[0])
+		switch cmd {
+		case "EHLO", "HELO":
+			reply("250-localhost")
+			reply("250 AUTH PLAIN")
+		case "AUTH":
+			decoded, _ := base64.StdEncoding.DecodeString(strings.Fields(line)[2])
+			m.auth = string(decoded)
+			reply("235 2.7.0 Authentication successful")
+		case "MAIL":
+			m.from = line[len("MAIL FROM:"):]
+			reply("250 OK")
+		case "RCPT":
+			m.to = append(m.to, line[len("RCPT TO:"):])
+			reply("250 OK")
+		case "DATA":
+			reply("354 End data with <CR><LF>.<CR><LF>")
+			var data strings.Builder
+			for {
+				l, err := r.ReadString('\n')
+				if err != nil {
+					return
+				}
+				if l == ".\r\n" {
+					break
+				}
+				data.WriteString(l)
+			}
+			m.data = data.String()
+			s.mu.Lock()
+			s.mails = append(s.mails, m)
+			s.mu.Unlock()
+			m = receivedMail{}
+			reply("250 OK")
+		case "QUIT":
+			reply("221 Bye")
+			return
+		default:
+			reply("250 OK")
+		}
+	}
+}
+
+func TestSMTPReporter(t *testing.T) {
+	stub := newSMTPStub(t)
+	defer stub.ln.Close()
+
+	result := *dummyResult
+	result.Searches = append([]formatter.SearchResult{}, dummyResult.Searches...)
+	result.Searches[1].MailTo = []string{"team@example.com"}
+
+	r := NewSMTPReporter(SMTPConfig{
+		Addr:     stub.ln.Addr().String(),
+		Username: "user",
+		Password: "pass",
+		From:     "diaper@example.com",
+		To:       []string{"security@example.com"},
+	})
+	if err := r.Report(context.Background(), &result); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(stub.mails) != 2 {
+		t.Fatalf("got: %v\nwant: %v", len(stub.mails), 2)
+	}
+	if stub.mails[0].auth != "\x00user\x00pass" {
+		t.Errorf("got: %q", stub.mails[0].auth)
+	}
+
+	// security@example.com receives all searches, team@example.com receives only test2
+	security, team := stub.mails[0], stub.mails[1]
+	if security.to[0] != "<security@example.com>" || team.to[0] != "<team@example.com>" {
+		t.Fatalf("got: %v, %v", security.to, team.to)
+	}
+
+	msg, err := mail.ReadMessage(strings.NewReader(security.data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	subject, err := new(mime.WordDecoder).DecodeHeader(msg.Header.Get("Subject"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasSuffix(subject, "1件") {
+		t.Errorf("got: %v", subject)
+	}
+
+	mediaType, params, err := mime.ParseMediaType(msg.Header.Get("Content-Type"))
+	if err != nil || mediaType != "multipart/alternative" {
+		t.Fatalf("got: %v, %v", mediaType, err)
+	}
+	mr := multipart.NewReader(msg.Body, params["boundary"])
+	var parts []string
+	for {
+		p, err := mr.NextPart()
+		if err != nil {
+			break
+		}
+		b, _ := ioutil.ReadAll(p)
+		parts = append(parts, p.Header.Get("Content-Type")+"\n"+string(b))
+	}
+	if len(parts) != 2 {
+		t.Fatalf("got: %v\nwant: %v", len(parts), 2)
+	}
+	if !strings.HasPrefix(parts[0], "text/plain") || !strings.Contains(parts[0], "test1の詳細結果:ghost/dummy-repo1") {
+		t.Errorf("got: %v", parts[0])
+	}
+	if !strings.HasPrefix(parts[1], "text/html") || !strings.Contains(parts[1], "detect dummy1-1") {
+		t.Errorf("got: %v", parts[1])
+	}
+
+	teamMsg, err := mail.ReadMessage(strings.NewReader(team.data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, _ := ioutil.ReadAll(teamMsg.Body)
+	if strings.Contains(string(b), "test1") {
+		t.Errorf("team@example.com must not receive test1: %v", string(b))
+	}
+}
@@ -0,0 +1,127 @@
+/**
+ * Copyright (c) 2019-present Future Corporation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package reporter
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/future-architect/code-diaper/formatter"
+	"io/ioutil"
+	"net/http"
+)
+
+// TeamsReporter posts Adaptive Cards to a Microsoft Teams incoming webhook.
+// Incoming webhooks can not reply to a message, so the details are posted as separate cards after the summary.
+type TeamsReporter struct {
+	client     *http.Client
+	webhookURL string
+}
+
+func NewTeamsReporter(webhookURL string) *TeamsReporter {
+	return &TeamsReporter{
+		client:     http.DefaultClient,
+		webhookURL: webhookURL,
+	}
+}
+
+// Report posts the summary card and a card for the details of each search.
+func (t TeamsReporter) Report(ctx context.Context, result *formatter.ScanResult) error {
+	summary, details, err := formatter.FmtText(*result)
+	if err != nil {
+		return err
+	}
+
+	if err := t.Post(ctx, "code-diaper 検索結果", summary); err != nil {
+		return err
+	}
+	for _, v := range details {
+		if err := t.Post(ctx, "", v); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type teamsMessage struct {
+	Type        string            `json:"type"`
+	Attachments []teamsAttachment `json:"attachments"`
+}
+
+type teamsAttachment struct {
+	ContentType string       `json:"contentType"`
+	Content     adaptiveCard `json:"content"`
+}
+
+// adaptiveCard https://adaptivecards.io/explorer/AdaptiveCard.html
+type adaptiveCard struct {
+	Schema  string              `json:"$schema"`
+	Type    string              `json:"type"`
+	Version string              `json:"version"`
+	Body    []adaptiveTextBlock `json:"body"`
+}
+
+type adaptiveTextBlock struct {
+	Type   string `json:"type"`
+	Text   string `json:"text"`
+	Wrap   bool   `json:"wrap"`
+	Size   string `json:"size,omitempty"`
+	Weight string `json:"weight,omitempty"`
+}
+
+// Post sends a card. title is omitted if empty.
+func (t TeamsReporter) Post(ctx context.Context, title, msg string) error {
+	var body []adaptiveTextBlock
+	if title != "" {
+		body = append(body, adaptiveTextBlock{Type: "TextBlock", Text: title, Wrap: true, Size: "Medium", Weight: "Bolder"})
+	}
+	body = append(body, adaptiveTextBlock{Type: "TextBlock", Text: msg, Wrap: true})
+
+	b, err := json.Marshal(teamsMessage{
+		Type: "message",
+		Attachments: []teamsAttachment{{
+			ContentType: "application/vnd.microsoft.card.adaptive",
+			Content: adaptiveCard{
+				Schema:  "http://adaptivecards.io/schemas/adaptive-card.json",
+				Type:    "AdaptiveCard",
+				Version: "1.2",
+				Body:    body,
+			},
+		}},
+	})
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, t.webhookURL, bytes.NewReader(b))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := t.client.Do(req.WithContext(ctx))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || 300 <= resp.StatusCode {
+		respBody, _ := ioutil.ReadAll(resp.Body)
+		return fmt.Errorf("teams webhook returned %v: %s", resp.Status, respBody)
+	}
+	return nil
+}
@@ -0,0 +1,81 @@
+/**
+ * Copyright (c) 2019-present Future Corporation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package reporter
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/future-architect/code-diaper/crawler"
+	"github.com/future-architect/code-diaper/formatter"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+var dummyResult = &formatter.ScanResult{
+	Searches: []formatter.SearchResult{
+		{
+			Query: "test1",
+			Repos: crawler.Repositories{
+				{URL: "https://github.com/ghost/dummy-repo1", Owner: "ghost", Name: "dummy-repo1",
+					HitFiles: crawler.Files{{URL: "https://github.com/ghost/dummy-repo1/dummy1.md", Fragments: []string{"detect dummy1-1"}}}},
+			},
+			HitCount: 1,
+		},
+		{Query: "test2"},
+	},
+}
+
+func TestTeamsReporter(t *testing.T) {
+	var cards []teamsMessage
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var m teamsMessage
+		if err := json.NewDecoder(r.Body).Decode(&m); err != nil {
+			t.Error(err)
+		}
+		cards = append(cards, m)
+		w.Write([]byte("1"))
+	}))
+	defer ts.Close()
+
+	if err := NewTeamsReporter(ts.URL).Report(context.Background(), dummyResult); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(cards) != 2 {
+		t.Fatalf("got: %v\nwant: %v", len(cards), 2)
+	}
+	summary := cards[0].Attachments[0].Content
+	if summary.Type != "AdaptiveCard" || len(summary.Body) != 2 || summary.Body[1].Text != "test1の検索結果: 1件\ntest2の検索結果: 0件" {
+		t.Errorf("got: %+v", summary)
+	}
+	if detail := cards[1].Attachments[0].Content.Body[0].Text; !strings.HasPrefix(detail, "test1の詳細結果:ghost/dummy-repo1") {
+		t.Errorf("got: %v", detail)
+	}
+}
+
+func TestTeamsReporterError(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte("Webhook message delivery failed"))
+	}))
+	defer ts.Close()
+
+	if err := NewTeamsReporter(ts.URL).Report(context.Background(), dummyResult); err == nil {
+		t.Errorf("got: nil\nwant: error")
+	}
+}
@@ -0,0 +1,144 @@
+/**
+ * Copyright (c) 2019-present Future Corporation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package reporter
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/future-architect/code-diaper/formatter"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"strconv"
+	"text/template"
+	"time"
+)
+
+const (
+	// SignatureHeader has "sha256=" and the hex HMAC-SHA256 of the timestamp, "." and the body by the secret.
+	SignatureHeader = "X-Code-Diaper-Signature"
+
+	// TimestampHeader has the unix time of the request in seconds. The receiver should reject an old one to prevent replay.
+	TimestampHeader = "X-Code-Diaper-Timestamp"
+)
+
+// WebhookReporter posts the scan result to an arbitrary URL.
+type WebhookReporter struct {
+	client     *http.Client
+	url        string
+	secret     string
+	template   *template.Template // nil means the JSON of the whole result
+	maxRetries int
+	retryWait  time.Duration // doubled on each retry
+}
+
+// NewWebhookReporter returns a reporter. The secret is required to sign the requests.
+// payloadTemplate is a text/template applied to formatter.ScanResult.
+// "json" function is available in the template. e.g. {"text": {{ json (index .Searches 0).Query }}}
+func NewWebhookReporter(url, secret, payloadTemplate string) (*WebhookReporter, error) {
+	if secret == "" {
+		return nil, errors.New("required parameter for webhook reporter: WebhookSecret")
+	}
+	r := &WebhookReporter{
+		client:     http.DefaultClient,
+		url:        url,
+		secret:     secret,
+		maxRetries: 3,
+		retryWait:  time.Second,
+	}
+	if payloadTemplate != "" {
+		t, err := template.New("webhook").Funcs(template.FuncMap{"json": toJSON}).Parse(payloadTemplate)
+		if err != nil {
+			return nil, fmt.Errorf("invalid webhook template: %v", err)
+		}
+		r.template = t
+	}
+	return r, nil
+}
+
+func toJSON(v interface{}) (string, error) {
+	b, err := json.Marshal(v)
+	return string(b), err
+}
+
+func (w WebhookReporter) Report(ctx context.Context, result *formatter.ScanResult) error {
+	var body bytes.Buffer
+	if w.template != nil {
+		if err := w.template.Execute(&body, result); err != nil {
+			return err
+		}
+	} else if err := json.NewEncoder(&body).Encode(result); err != nil {
+		return err
+	}
+
+	wait := w.retryWait
+	for i := 0; ; i++ {
+		retryable, err := w.post(ctx, body.Bytes())
+		if err == nil {
+			return nil
+		}
+		if !retryable || i >= w.maxRetries {
+			return err
+		}
+		log.Printf("webhook failed: %v. retry after %v\n", err, wait)
+
+		select {
+		case <-time.After(wait):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+		wait *= 2
+	}
+}
+
+// Sign returns the signature header value of the timestamp header value and the body.
+func Sign(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp + "."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// post sends the body once. Network errors and 5xx are retryable.
+func (w WebhookReporter) post(ctx context.Context, body []byte) (bool, error) {
+	req, err := http.NewRequest(http.MethodPost, w.url, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	// signed on each retry, so that the timestamp is fresh
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(TimestampHeader, timestamp)
+	req.Header.Set(SignatureHeader, Sign(w.secret, timestamp, body))
+
+	resp, err := w.client.Do(req.WithContext(ctx))
+	if err != nil {
+		return ctx.Err() == nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || 300 <= resp.StatusCode {
+		respBody, _ := ioutil.ReadAll(resp.Body)
+		return resp.StatusCode >= 500, fmt.Errorf("webhook returned %v: %s", resp.Status, respBody)
+	}
+	return false, nil
+}
@@ -0,0 +1,124 @@
+/**
+ * Copyright (c) 2019-present Future Corporation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package reporter
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/future-architect/code-diaper/formatter"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestWebhookReporter(t *testing.T) {
+	requests := 0
+	var received formatter.ScanResult
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if requests < 3 {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		body, _ := ioutil.ReadAll(r.Body)
+		timestamp := r.Header.Get(TimestampHeader)
+		if sent, err := strconv.ParseInt(timestamp, 10, 64); err != nil || time.Since(time.Unix(sent, 0)) > time.Minute {
+			t.Errorf("got: %v\nwant: current unix time", timestamp)
+		}
+		if r.Header.Get(SignatureHeader) != Sign("dummy-secret", timestamp, body) {
+			t.Errorf("got: %v\nwant: %v", r.Header.Get(SignatureHeader), Sign("dummy-secret", timestamp, body))
+		}
+		if err := json.Unmarshal(body, &received); err != nil {
+			t.Error(err)
+		}
+	}))
+	defer ts.Close()
+
+	r, err := NewWebhookReporter(ts.URL, "dummy-secret", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.retryWait = 0
+
+	if err := r.Report(context.Background(), dummyResult); err != nil {
+		t.Fatal(err)
+	}
+	if requests != 3 {
+		t.Errorf("got: %v\nwant: %v", requests, 3)
+	}
+	if len(received.Searches) != 2 || received.Searches[0].Repos[0].Name != "dummy-repo1" {
+		t.Errorf("got: %+v", received)
+	}
+}
+
+func TestWebhookReporterTemplate(t *testing.T) {
+	var body string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := ioutil.ReadAll(r.Body)
+		body = string(b)
+	}))
+	defer ts.Close()
+
+	r, err := NewWebhookReporter(ts.URL, "dummy-secret", `{"text": {{ json (index .Searches 0).Query }}, "hits": {{ (index .Searches 0).HitCount }}}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Report(context.Background(), dummyResult); err != nil {
+		t.Fatal(err)
+	}
+	if body != `{"text": "test1", "hits": 1}` {
+		t.Errorf("got: %v\nwant: %v", body, `{"text": "test1", "hits": 1}`)
+	}
+}
+
+func TestWebhookReporterClientError(t *testing.T) {
+	requests := 0
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.WriteHeader(http.StatusBadRequest)
+	}))
+	defer ts.Close()
+
+	r, err := NewWebhookReporter(ts.URL, "dummy-secret", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.retryWait = 0
+
+	if err := r.Report(context.Background(), dummyResult); err == nil {
+		t.Errorf("got: nil\nwant: error")
+	}
+	if requests != 1 {
+		t.Errorf("4xx must not be retried. got: %v requests", requests)
+	}
+}
+
+func TestWebhookReporterSecretRequired(t *testing.T) {
+	if _, err := NewWebhookReporter("https://example.com/hook", "", ""); err == nil {
+		t.Errorf("got: nil\nwant: error")
+	}
+}
+
+func TestSign(t *testing.T) {
+	body := []byte(`{"searches":[]}`)
+	// the same body at another time has another signature, so that a captured request can not be replayed later
+	if Sign("dummy-secret", "1564617600", body) == Sign("dummy-secret", "1564617601", body) {
+		t.Error("timestamp must be signed")
+	}
+}
@@ -0,0 +1,361 @@
+/**
+ * Copyright (c) 2019-present Future Corporation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package store
+
+import (
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"github.com/future-architect/code-diaper/crawler"
+	"strings"
+	"time"
+)
+
+type Status string
+
+const (
+	StatusNew      Status = "new"      // found for the first time, or found again after resolved
+	StatusPresent  Status = "present"  // found in the previous run too
+	StatusResolved Status = "resolved" // not found any more
+)
+
+type TriageState string
+
+const (
+	TriageAcknowledged  TriageState = "acknowledged"   // known and being handled
+	TriageFalsePositive TriageState = "false_positive" // not a leak
+	TriageRemediated    TriageState = "remediated"     // taken down. it is reported again if found
+)
+
+// Triage is the decision made by a person for a finding.
+type Triage struct {
+	State     TriageState `json:"state"`
+	Note      string      `json:"note,omitempty"`
+	UpdatedAt time.Time   `json:"updated_at"`
+}
+
+// ParseTriageState accepts both "false-positive" and "false_positive".
+func ParseTriageState(s string) (TriageState, error) {
+	state := TriageState(strings.Replace(strings.ToLower(s), "-", "_", -1))
+	switch state {
+	case TriageAcknowledged, TriageFalsePositive, TriageRemediated:
+		return state, nil
+	}
+	return "", fmt.Errorf("unknown triage state: %v. acknowledged, false-positive or remediated", s)
+}
+
+// Suppresses reports whether the finding should not be reported any more.
+func (t *Triage) Suppresses() bool {
+	return t != nil && (t.State == TriageAcknowledged || t.State == TriageFalsePositive)
+}
+
+// Finding is a fragment of a file detected by queries. It is identified by repository + file path + fragment hash,
+// so that a push that changes the commit in the file URL does not make it another finding.
+type Finding struct {
+	ID            string     `json:"id"`
+	Queries       []string   `json:"queries"`
+	RepositoryURL string     `json:"repository_url"`
+	Path          string     `json:"path"`
+	FileURL       string     `json:"file_url"` // the latest URL
+	FragmentHash  string     `json:"fragment_hash"`
+	Fragment      string     `json:"fragment"`
+	Status        Status     `json:"status"`
+	FirstSeen     time.Time  `json:"first_seen"`
+	LastSeen      time.Time  `json:"last_seen"`
+	ResolvedAt    *time.Time `json:"resolved_at,omitempty"`
+	Triage        *Triage    `json:"triage,omitempty"`
+}
+
+// UnmarshalJSON also accepts the findings saved before the ID was based on the path,
+// and recomputes the ID so that they match the findings of this run.
+func (f *Finding) UnmarshalJSON(b []byte) error {
+	type finding Finding
+	var v struct {
+		finding
+		Query string `json:"query"` // a finding had only one query
+	}
+	if err := json.Unmarshal(b, &v); err != nil {
+		return err
+	}
+	*f = Finding(v.finding)
+	if len(f.Queries) == 0 && v.Query != "" {
+		f.Queries = []string{v.Query}
+	}
+	if f.Path == "" {
+		f.Path = pathOf(f.FileURL)
+	}
+	f.FragmentHash = FragmentHash(f.Fragment)
+	f.ID = findingID(f.RepositoryURL, f.Path, f.Fragment)
+	return nil
+}
+
+// HasQuery reports whether the finding is detected by the query.
+func (f Finding) HasQuery(query string) bool {
+	for _, v := range f.Queries {
+		if v == query {
+			return true
+		}
+	}
+	return false
+}
+
+type Findings []Finding
+
+// Diff is the result of Reconcile. New, Present and Resolved are for this run. All is to be saved.
+type Diff struct {
+	New      Findings
+	Present  Findings
+	Resolved Findings
+	All      Findings
+}
+
+// FindingID returns the short ID of the finding. It is printed in the output and used to specify the finding.
+func FindingID(repositoryURL string, file crawler.File, fragment string) string {
+	return findingID(repositoryURL, FilePath(file), fragment)
+}
+
+func findingID(repositoryURL, path, fragment string) string {
+	sum := sha1.Sum([]byte(repositoryURL + "\n" + path + "\n" + FragmentHash(fragment)))
+	return hex.EncodeToString(sum[:])[:12]
+}
+
+// FragmentHash ignores the differences of white spaces, which the search API may return differently.
+func FragmentHash(fragment string) string {
+	sum := sha256.Sum256([]byte(strings.Join(strings.Fields(fragment), " ")))
+	return hex.EncodeToString(sum[:])
+}
+
+// FilePath returns the path of the file in the repository. The URL is used if the crawler does not know the path.
+func FilePath(file crawler.File) string {
+	if file.Path != "" {
+		return file.Path
+	}
+	return pathOf(file.URL)
+}
+
+// pathOf takes the path out of the file URL, which has the commit SHA or the branch.
+// e.g. "https://github.com/owner/repo/blob/<sha>/<path>" or "<sha>:<path>" of the local history.
+func pathOf(fileURL string) string {
+	if i := strings.Index(fileURL, "/blob/"); i >= 0 {
+		rest := fileURL[i+len("/blob/"):]
+		if j := strings.Index(rest, "/"); j >= 0 {
+			return rest[j+1:]
+		}
+		return rest
+	}
+	if i := strings.Index(fileURL, ":"); i >= 0 && !strings.Contains(fileURL, "://") {
+		return fileURL[i+1:]
+	}
+	return fileURL
+}
+
+// NewFindings converts a search result to findings. Each fragment becomes one finding.
+func NewFindings(query string, repos crawler.Repositories, now time.Time) Findings {
+	var result Findings
+	for _, r := range repos {
+		for _, f := range r.HitFiles {
+			for _, fragment := range f.Fragments {
+				result = result.Merge(Finding{
+					ID:            FindingID(r.URL, f, fragment),
+					Queries:       []string{query},
+					RepositoryURL: r.URL,
+					Path:          FilePath(f),
+					FileURL:       f.URL,
+					FragmentHash:  FragmentHash(fragment),
+					Fragment:      fragment,
+					Status:        StatusNew,
+					FirstSeen:     now,
+					LastSeen:      now,
+				})
+			}
+		}
+	}
+	return result
+}
+
+func (fs Findings) Index(id string) int {
+	for i, f := range fs {
+		if f.ID == id {
+			return i
+		}
+	}
+	return -1
+}
+
+// Merge appends the finding if it is not contained yet. Otherwise the queries are added to the contained one.
+func (fs Findings) Merge(f Finding) Findings {
+	idx := fs.Index(f.ID)
+	if idx == -1 {
+		return append(fs, f)
+	}
+	fs[idx].Queries = mergeQueries(fs[idx].Queries, f.Queries)
+	return fs
+}
+
+func mergeQueries(queries, others []string) []string {
+	result := append([]string{}, queries...)
+	for _, q := range others {
+		if !contains(result, q) {
+			result = append(result, q)
+		}
+	}
+	return result
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// Contains reports whether the file has the fragment in the findings.
+func (fs Findings) Contains(repositoryURL string, file crawler.File, fragment string) bool {
+	return fs.Index(FindingID(repositoryURL, file, fragment)) != -1
+}
+
+// Reconcile classifies the findings of this run by comparing with the stored findings.
+// Only stored findings that searched returns true are resolved,
+// because the other queries were not executed (or not fetched completely) in this run.
+func Reconcile(stored, current Findings, searched func(Finding) bool, now time.Time) Diff {
+	var diff Diff
+
+	for _, c := range current {
+		idx := stored.Index(c.ID)
+		if idx == -1 {
+			diff.New = append(diff.New, c)
+			continue
+		}
+
+		s := stored[idx]
+		s.LastSeen = now
+		s.FileURL = c.FileURL
+		s.Queries = mergeQueries(s.Queries, c.Queries)
+		if s.Status == StatusResolved {
+			s.Status = StatusNew
+			s.ResolvedAt = nil
+			diff.New = append(diff.New, s)
+		} else {
+			s.Status = StatusPresent
+			diff.Present = append(diff.Present, s)
+		}
+	}
+
+	var rest Findings
+	for _, s := range stored {
+		if current.Index(s.ID) != -1 {
+			continue
+		}
+		if s.Status != StatusResolved && searched(s) {
+			s.Status = StatusResolved
+			resolvedAt := now
+			s.ResolvedAt = &resolvedAt
+			diff.Resolved = append(diff.Resolved, s)
+			continue
+		}
+		rest = append(rest, s)
+	}
+
+	diff.All = append(diff.All, diff.New...)
+	diff.All = append(diff.All, diff.Present...)
+	diff.All = append(diff.All, diff.Resolved...)
+	diff.All = append(diff.All, rest...)
+	return diff
+}
+
+// Query returns the findings of the query.
+func (fs Findings) Query(query string) Findings {
+	var result Findings
+	for _, f := range fs {
+		if f.HasQuery(query) {
+			result = append(result, f)
+		}
+	}
+	return result
+}
+
+// SetTriage records the decision for the finding of the id.
+func (fs Findings) SetTriage(id string, state TriageState, note string, now time.Time) (Findings, error) {
+	idx := fs.Index(id)
+	if idx == -1 {
+		return nil, fmt.Errorf("finding not found: %v", id)
+	}
+
+	result := make(Findings, len(fs))
+	copy(result, fs)
+	result[idx].Triage = &Triage{
+		State:     state,
+		Note:      note,
+		UpdatedAt: now,
+	}
+	return result, nil
+}
+
+// Unsuppressed returns the findings that are not triaged as false positive or acknowledged.
+func (fs Findings) Unsuppressed() Findings {
+	var result Findings
+	for _, f := range fs {
+		if !f.Triage.Suppresses() {
+			result = append(result, f)
+		}
+	}
+	return result
+}
+
+// ResolvedRepositories returns the repository URLs all of whose findings are resolved.
+func (fs Findings) ResolvedRepositories() []string {
+	var result []string
+	remaining := map[string]bool{}
+	for _, f := range fs {
+		if f.Status != StatusResolved {
+			remaining[f.RepositoryURL] = true
+		}
+	}
+	seen := map[string]bool{}
+	for _, f := range fs {
+		if f.Status == StatusResolved && !remaining[f.RepositoryURL] && !seen[f.RepositoryURL] {
+			seen[f.RepositoryURL] = true
+			result = append(result, f.RepositoryURL)
+		}
+	}
+	return result
+}
+
+// FileURLs returns the file URLs of the findings without duplication.
+func (fs Findings) FileURLs() []string {
+	var result []string
+	seen := map[string]bool{}
+	for _, f := range fs {
+		if !seen[f.FileURL] {
+			seen[f.FileURL] = true
+			result = append(result, f.FileURL)
+		}
+	}
+	return result
+}
+
+// IDs returns the IDs of the findings.
+func (fs Findings) IDs() []string {
+	result := make([]string, 0, len(fs))
+	for _, f := range fs {
+		result = append(result, f.ID)
+	}
+	return result
+}
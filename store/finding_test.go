@@ -0,0 +1,214 @@
+/**
+ * Copyright (c) 2019-present Future Corporation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package store
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/future-architect/code-diaper/crawler"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func repos(fileNames ...string) crawler.Repositories {
+	var files crawler.Files
+	for _, v := range fileNames {
+		files = append(files, crawler.File{URL: "https://github.com/ghost/dummy/" + v, Fragments: []string{"Copyright 2019 Example"}})
+	}
+	return crawler.Repositories{{URL: "https://github.com/ghost/dummy", Owner: "ghost", Name: "dummy", HitFiles: files}}
+}
+
+func all(Finding) bool {
+	return true
+}
+
+func TestReconcile(t *testing.T) {
+	day1 := time.Date(2019, 8, 1, 0, 0, 0, 0, time.UTC)
+	day2 := day1.AddDate(0, 0, 1)
+	day3 := day2.AddDate(0, 0, 1)
+
+	first := Reconcile(Findings{}, NewFindings("q", repos("a.go", "b.go"), day1), all, day1)
+	if len(first.New) != 2 || len(first.Present) != 0 || len(first.Resolved) != 0 {
+		t.Fatalf("got: %+v", first)
+	}
+
+	second := Reconcile(first.All, NewFindings("q", repos("b.go", "c.go"), day2), all, day2)
+	if len(second.New) != 1 || second.New[0].FileURL != "https://github.com/ghost/dummy/c.go" {
+		t.Errorf("got: %+v", second.New)
+	}
+	if len(second.Present) != 1 || second.Present[0].FirstSeen != day1 || second.Present[0].LastSeen != day2 {
+		t.Errorf("got: %+v", second.Present)
+	}
+	if len(second.Resolved) != 1 || second.Resolved[0].FileURL != "https://github.com/ghost/dummy/a.go" {
+		t.Errorf("got: %+v", second.Resolved)
+	}
+
+	// resolved finding is not announced again, but it is new when it appears again
+	third := Reconcile(second.All, NewFindings("q", repos("a.go", "b.go", "c.go"), day3), all, day3)
+	if len(third.New) != 1 || third.New[0].FileURL != "https://github.com/ghost/dummy/a.go" || third.New[0].ResolvedAt != nil {
+		t.Errorf("got: %+v", third.New)
+	}
+	if len(third.Resolved) != 0 || len(third.All) != 3 {
+		t.Errorf("got: %+v", third)
+	}
+}
+
+func TestReconcileNotSearched(t *testing.T) {
+	now := time.Now()
+	stored := NewFindings("q", repos("a.go"), now)
+
+	diff := Reconcile(stored, Findings{}, func(Finding) bool { return false }, now)
+	if len(diff.Resolved) != 0 || len(diff.All) != 1 {
+		t.Errorf("got: %+v", diff)
+	}
+}
+
+func TestResolvedRepositories(t *testing.T) {
+	day1 := time.Date(2019, 8, 1, 0, 0, 0, 0, time.UTC)
+	day2 := day1.AddDate(0, 0, 1)
+	first := Reconcile(Findings{}, NewFindings("q", repos("a.go", "b.go"), day1), all, day1)
+
+	partly := Reconcile(first.All, NewFindings("q", repos("b.go"), day2), all, day2)
+	if actual := partly.All.ResolvedRepositories(); len(actual) != 0 {
+		t.Errorf("got: %v\nwant: []", actual)
+	}
+
+	resolved := Reconcile(first.All, Findings{}, all, day2)
+	if actual := resolved.All.ResolvedRepositories(); len(actual) != 1 || actual[0] != "https://github.com/ghost/dummy" {
+		t.Errorf("got: %v\nwant: %v", actual, []string{"https://github.com/ghost/dummy"})
+	}
+}
+
+func TestFileStore(t *testing.T) {
+	dir, err := ioutil.TempDir("", "codediaper")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	ctx := context.Background()
+	st := NewFileStore(filepath.Join(dir, "findings.json"))
+
+	empty, err := st.Load(ctx)
+	if err != nil || len(empty) != 0 {
+		t.Fatalf("got: %v, %v", empty, err)
+	}
+
+	expected := NewFindings("q", repos("a.go", "b.go"), time.Now())
+	if err := st.Save(ctx, expected); err != nil {
+		t.Fatal(err)
+	}
+	actual, err := st.Load(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(actual) != 2 || actual[1].ID != expected[1].ID {
+		t.Errorf("got: %+v\nwant: %+v", actual, expected)
+	}
+}
+
+func TestSetTriage(t *testing.T) {
+	now := time.Now()
+	findings := NewFindings("q", repos("a.go", "b.go"), now)
+
+	state, err := ParseTriageState("false-positive")
+	if err != nil {
+		t.Fatal(err)
+	}
+	actual, err := findings.SetTriage(findings[0].ID, state, "sample code", now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !actual[0].Triage.Suppresses() || actual[0].Triage.Note != "sample code" || findings[0].Triage != nil {
+		t.Errorf("got: %+v", actual[0])
+	}
+	if len(actual.Unsuppressed()) != 1 {
+		t.Errorf("got: %v\nwant: %v", len(actual.Unsuppressed()), 1)
+	}
+
+	if _, err := findings.SetTriage("unknown", state, "", now); err == nil {
+		t.Error("expected error for unknown id")
+	}
+	if _, err := ParseTriageState("ignored"); err == nil {
+		t.Error("expected error for unknown state")
+	}
+}
+
+func blobRepos(sha string, fileNames ...string) crawler.Repositories {
+	var files crawler.Files
+	for _, v := range fileNames {
+		files = append(files, crawler.File{URL: "https://github.com/ghost/dummy/blob/" + sha + "/" + v, Path: v, Fragments: []string{"Copyright 2019 Example"}})
+	}
+	return crawler.Repositories{{URL: "https://github.com/ghost/dummy", Owner: "ghost", Name: "dummy", HitFiles: files}}
+}
+
+func TestReconcileAfterPush(t *testing.T) {
+	day1 := time.Date(2019, 8, 1, 0, 0, 0, 0, time.UTC)
+	day2 := day1.AddDate(0, 0, 1)
+
+	first := Reconcile(Findings{}, NewFindings("q", blobRepos("aaa", "a.go"), day1), all, day1)
+	second := Reconcile(first.All, NewFindings("q", blobRepos("bbb", "a.go"), day2), all, day2)
+	if len(second.New) != 0 || len(second.Resolved) != 0 || len(second.Present) != 1 {
+		t.Fatalf("got: %+v", second)
+	}
+	if second.Present[0].FileURL != "https://github.com/ghost/dummy/blob/bbb/a.go" {
+		t.Errorf("got: %v", second.Present[0].FileURL)
+	}
+}
+
+func TestFindingsMergeQueries(t *testing.T) {
+	now := time.Now()
+	var current Findings
+	for _, f := range NewFindings("q1", repos("a.go"), now) {
+		current = current.Merge(f)
+	}
+	for _, f := range NewFindings("q2", repos("a.go"), now) {
+		current = current.Merge(f)
+	}
+	if len(current) != 1 || len(current.Query("q1")) != 1 || len(current.Query("q2")) != 1 {
+		t.Errorf("got: %+v", current)
+	}
+}
+
+func TestFindingUnmarshalLegacy(t *testing.T) {
+	var actual Finding
+	err := json.Unmarshal([]byte(`{"id": "c95ac5aef243", "query": "q", "repository_url": "https://github.com/ghost/dummy",
+		"file_url": "https://github.com/ghost/dummy/blob/aaa/src/a.go", "fragment": "Copyright 2019 Example"}`), &actual)
+	if err != nil {
+		t.Fatal(err)
+	}
+	file := crawler.File{URL: "https://github.com/ghost/dummy/blob/bbb/src/a.go", Path: "src/a.go"}
+	if actual.Path != "src/a.go" || !actual.HasQuery("q") || actual.ID != FindingID("https://github.com/ghost/dummy", file, "Copyright 2019 Example") {
+		t.Errorf("got: %+v", actual)
+	}
+}
+
+func TestPathOf(t *testing.T) {
+	for input, expected := range map[string]string{
+		"https://github.com/ghost/dummy/blob/3f2a1b/src/a.go":   "src/a.go",
+		"https://gitlab.com/ghost/dummy/-/blob/master/src/a.go": "src/a.go",
+		"2c9f1b4:src/a.go":                    "src/a.go",
+		"src/a.go":                            "src/a.go",
+		"https://github.com/ghost/dummy/a.go": "https://github.com/ghost/dummy/a.go",
+	} {
+		if actual := pathOf(input); actual != expected {
+			t.Errorf("got: %v\nwant: %v", actual, expected)
+		}
+	}
+}
@@ -0,0 +1,120 @@
+/**
+ * Copyright (c) 2019-present Future Corporation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package store
+
+import (
+	"cloud.google.com/go/storage"
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"strings"
+	"sync"
+)
+
+// gcsScheme is the prefix of the store path for an object of Cloud Storage.
+const gcsScheme = "gs://"
+
+// gcsStore keeps all findings in one object of Cloud Storage, so that the instances of Cloud Functions share them.
+type gcsStore struct {
+	mu         sync.Mutex
+	object     *storage.ObjectHandle
+	generation int64 // of the last Load. 0 means the object did not exist
+}
+
+// NewStore returns the store for the path. "gs://bucket/object" is an object of Cloud Storage, otherwise a local file.
+func NewStore(ctx context.Context, path string) (Store, error) {
+	bucket, object, ok := parseGCSPath(path)
+	if !ok {
+		return NewFileStore(path), nil
+	}
+	client, err := storage.NewClient(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &gcsStore{
+		object: client.Bucket(bucket).Object(object),
+	}, nil
+}
+
+// IsShared reports whether the store of the path is shared by the instances of Cloud Functions.
+func IsShared(path string) bool {
+	_, _, ok := parseGCSPath(path)
+	return ok
+}
+
+func parseGCSPath(path string) (bucket, object string, ok bool) {
+	if !strings.HasPrefix(path, gcsScheme) {
+		return "", "", false
+	}
+	split := strings.SplitN(strings.TrimPrefix(path, gcsScheme), "/", 2)
+	if len(split) != 2 || split[0] == "" || split[1] == "" {
+		return "", "", false
+	}
+	return split[0], split[1], true
+}
+
+// Load returns empty findings if the object does not exist yet.
+func (s *gcsStore) Load(ctx context.Context) (Findings, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	r, err := s.object.NewReader(ctx)
+	if err == storage.ErrObjectNotExist {
+		s.generation = 0
+		return Findings{}, nil
+	} else if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	b, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	var fs Findings
+	if err := json.Unmarshal(b, &fs); err != nil {
+		return nil, err
+	}
+	s.generation = r.Attrs.Generation
+	return fs, nil
+}
+
+// Save fails if another instance has saved since the last Load, instead of overwriting its findings.
+func (s *gcsStore) Save(ctx context.Context, fs Findings) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	b, err := json.MarshalIndent(fs, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	cond := storage.Conditions{GenerationMatch: s.generation}
+	if s.generation == 0 {
+		cond = storage.Conditions{DoesNotExist: true}
+	}
+	w := s.object.If(cond).NewWriter(ctx)
+	w.ContentType = "application/json"
+	if _, err := w.Write(b); err != nil {
+		w.Close()
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	s.generation = w.Attrs().Generation
+	return nil
+}
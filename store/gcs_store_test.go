@@ -0,0 +1,40 @@
+/**
+ * Copyright (c) 2019-present Future Corporation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package store
+
+import (
+	"testing"
+)
+
+func TestParseGCSPath(t *testing.T) {
+	tests := []struct {
+		input  string
+		bucket string
+		object string
+		ok     bool
+	}{
+		{"gs://leak-tracking/findings.json", "leak-tracking", "findings.json", true},
+		{"gs://leak-tracking/code-diaper/findings.json", "leak-tracking", "code-diaper/findings.json", true},
+		{"gs://leak-tracking", "", "", false},
+		{"./findings.json", "", "", false},
+	}
+	for _, tt := range tests {
+		bucket, object, ok := parseGCSPath(tt.input)
+		if bucket != tt.bucket || object != tt.object || ok != tt.ok {
+			t.Errorf("%v got: %v, %v, %v\nwant: %v, %v, %v", tt.input, bucket, object, ok, tt.bucket, tt.object, tt.ok)
+		}
+	}
+}
@@ -0,0 +1,88 @@
+/**
+ * Copyright (c) 2019-present Future Corporation
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package store
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// Store persists findings across runs.
+type Store interface {
+	Load(ctx context.Context) (Findings, error)
+	Save(ctx context.Context, fs Findings) error
+}
+
+type fileStore struct {
+	mu   sync.Mutex
+	path string
+}
+
+// NewFileStore returns a store that keeps all findings in one local JSON file.
+func NewFileStore(path string) Store {
+	return &fileStore{
+		path: path,
+	}
+}
+
+// Load returns empty findings if the file does not exist yet.
+func (s *fileStore) Load(ctx context.Context) (Findings, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	b, err := ioutil.ReadFile(s.path)
+	if os.IsNotExist(err) {
+		return Findings{}, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	var fs Findings
+	if err := json.Unmarshal(b, &fs); err != nil {
+		return nil, err
+	}
+	return fs, nil
+}
+
+// Save writes to a temporary file and renames it, so that the file is not broken by a failure while writing.
+func (s *fileStore) Save(ctx context.Context, fs Findings) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	b, err := json.MarshalIndent(fs, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tmp, err := ioutil.TempFile(filepath.Dir(s.path), filepath.Base(s.path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(b); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), s.path)
+}